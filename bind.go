@@ -0,0 +1,95 @@
+package pgs
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// boundMarker prefixes the placeholder values of bound values, NUL can not occur in PostgreSQL text.
+const boundMarker = "\x00pgs:"
+
+// binding collects the values bound by the prepared query being built, see bindSQL.
+// Queries are built under bindingMu: prepared ones exclusively, the others shared.
+var (
+	bindingMu sync.RWMutex
+	binding   *[]driver.Valuer
+)
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// boundValue is a driver.Valuer bound as is in prepared queries. goqu binds the result of Value(),
+// so a pgtype.Numeric would be sent as text instead of being encoded by pgx.
+type boundValue struct {
+	valuer driver.Valuer
+}
+
+func (v boundValue) Value() (driver.Value, error) {
+	if binding == nil {
+		return v.valuer.Value()
+	}
+	*binding = append(*binding, v.valuer)
+	return boundMarker + strconv.Itoa(len(*binding)-1), nil
+}
+
+// bind wraps a non NULL driver.Valuer into a boundValue, and the elements of slices of them (IN lists).
+func bind(value interface{}) interface{} {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if isNull(valuer) {
+			return value
+		}
+		return boundValue{valuer: valuer}
+	}
+	rValue := reflect.ValueOf(value)
+	if rValue.Kind() != reflect.Slice {
+		return value
+	}
+	if elem := rValue.Type().Elem(); elem.Kind() != reflect.Interface && !elem.Implements(valuerType) {
+		return value
+	}
+	values := make([]interface{}, rValue.Len())
+	for i := range values {
+		values[i] = bind(rValue.Index(i).Interface())
+	}
+	return values
+}
+
+type sqlBuilder interface {
+	ToSQL() (string, []interface{}, error)
+	IsPrepared() bool
+}
+
+// bindSQL builds the query of dataset, in prepared mode with the original values of bound values as arguments.
+func bindSQL(dataset sqlBuilder) (string, []interface{}, error) {
+	if !dataset.IsPrepared() {
+		bindingMu.RLock()
+		defer bindingMu.RUnlock()
+		return dataset.ToSQL()
+	}
+	bindingMu.Lock()
+	defer bindingMu.Unlock()
+	var values []driver.Valuer
+	binding = &values
+	defer func() {
+		binding = nil
+	}()
+	query, args, err := dataset.ToSQL()
+	if err != nil {
+		return "", nil, err
+	}
+	for i, arg := range args {
+		marker, ok := arg.(string)
+		if !ok || !strings.HasPrefix(marker, boundMarker) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(marker, boundMarker))
+		if err != nil || n >= len(values) {
+			return "", nil, fmt.Errorf("unknown bound value %q", marker)
+		}
+		args[i] = values[n]
+	}
+	return query, args, nil
+}
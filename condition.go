@@ -36,36 +36,42 @@ func (c Condition) Condition(inUpdate bool) (exp.Expression, error) {
 			ident = goqu.I(fmt.Sprintf("%s.%s", model.joiner.ParentTable, model.joiner.From))
 		}
 	}
+	value := c.Value
+	if field, ok := value.(fieldI); ok {
+		value = field.getIdent()
+	} else {
+		value = bind(value)
+	}
 	var condition exp.Expression
 	switch c.Op {
 	case opIn:
-		condition = ident.In(c.Value)
+		condition = ident.In(value)
 	case opNotIn:
-		condition = ident.NotIn(c.Value)
+		condition = ident.NotIn(value)
 	case opEq:
-		condition = ident.Eq(c.Value)
+		condition = ident.Eq(value)
 	case opNotEq:
-		condition = ident.Neq(c.Value)
+		condition = ident.Neq(value)
 	case opLike:
-		condition = ident.Like(c.Value)
+		condition = ident.Like(value)
 	case opNotLike:
-		condition = ident.NotLike(c.Value)
+		condition = ident.NotLike(value)
 	case opRegex:
-		condition = ident.RegexpLike(c.Value)
+		condition = ident.RegexpLike(value)
 	case opRegexI:
-		condition = ident.RegexpILike(c.Value)
+		condition = ident.RegexpILike(value)
 	case opNotRegex:
-		condition = ident.RegexpNotLike(c.Value)
+		condition = ident.RegexpNotLike(value)
 	case opNotRegexI:
-		condition = ident.RegexpNotILike(c.Value)
+		condition = ident.RegexpNotILike(value)
 	case opLt:
-		condition = ident.Lt(c.Value)
+		condition = ident.Lt(value)
 	case opLte:
-		condition = ident.Lte(c.Value)
+		condition = ident.Lte(value)
 	case opGt:
-		condition = ident.Gt(c.Value)
+		condition = ident.Gt(value)
 	case opGte:
-		condition = ident.Gte(c.Value)
+		condition = ident.Gte(value)
 	case opIsNotNull:
 		condition = ident.IsNotNull()
 	case opIsNull:
@@ -77,6 +83,9 @@ func (c Condition) Condition(inUpdate bool) (exp.Expression, error) {
 }
 
 func (c Condition) getJoiners() []*joiner {
+	if field, ok := c.Value.(fieldI); ok {
+		return append(c.joiners[:len(c.joiners):len(c.joiners)], field.getJoiners()...)
+	}
 	return c.joiners
 }
 
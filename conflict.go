@@ -0,0 +1,112 @@
+package pgs
+
+import (
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"strings"
+)
+
+const conflictDoNothing = " ON CONFLICT DO NOTHING"
+
+var excludedModel = &Model{tableName: "excluded"}
+
+// Excluded returns the field of the EXCLUDED pseudo-row of INSERT ... ON CONFLICT DO UPDATE.
+func Excluded[T any](field *Field[T]) *Field[T] {
+	name := field.field
+	if field.model != nil && field.model.joiner != nil {
+		name = field.model.joiner.From
+	}
+	excluded := &Field[T]{}
+	excluded.init(excludedModel, name, fieldOptions{})
+	return excluded
+}
+
+// ConflictDataset describes the conflict target of INSERT ... ON CONFLICT.
+type ConflictDataset struct {
+	insert *InsertDataset
+	target string
+}
+
+// OnConflict starts ON CONFLICT (fields...). Without fields any conflict is handled,
+// which is only allowed with DoNothing.
+func (d *InsertDataset) OnConflict(target ...fieldI) *ConflictDataset {
+	var columns []string
+	for _, field := range target {
+		column, err := d.model.column(field)
+		if err != nil {
+			d.setError(err)
+			continue
+		}
+		columns = append(columns, fmt.Sprintf(`"%s"`, column))
+	}
+	return &ConflictDataset{insert: d, target: strings.Join(columns, ", ")}
+}
+
+// OnConstraint starts ON CONFLICT ON CONSTRAINT name.
+func (d *InsertDataset) OnConstraint(name string) *ConflictDataset {
+	return &ConflictDataset{insert: d, target: fmt.Sprintf(`ON CONSTRAINT "%s"`, name)}
+}
+
+// DoNothing skips conflicting rows. The read back of InsertModel is turned off
+// because the returned rows no longer match the models, use Returning and Scan instead.
+func (c *ConflictDataset) DoNothing() *InsertDataset {
+	c.insert.writeBack = nil
+	c.insert.dataset = c.insert.dataset.OnConflict(goqu.DoNothing())
+	c.insert.conflictTarget = c.target
+	return c.insert
+}
+
+// DoUpdate sets the record on conflict. Use Excluded to refer to the proposed row,
+// conditions are added as DO UPDATE ... WHERE. Without a conflict target
+// the primary key of the model is used. With conditions the read back of InsertModel
+// is turned off as for DoNothing.
+func (c *ConflictDataset) DoUpdate(record Record, conditions ...Conditional) *InsertDataset {
+	d := c.insert
+	if c.target == "" && len(d.model.primaryKey) > 0 {
+		c.target = d.OnConflict(d.model.primaryKey...).target
+	}
+	if c.target == "" {
+		d.setError(fmt.Errorf("on conflict do update requires a conflict target"))
+		return d
+	}
+	values, err := record.toMap(d.model)
+	if err != nil {
+		d.setError(err)
+		return d
+	}
+	var exps []exp.Expression
+	for _, condition := range conditions {
+		cond, err := condition.Condition(false)
+		if err != nil {
+			d.setError(err)
+			continue
+		}
+		exps = append(exps, cond)
+	}
+	update := goqu.DoUpdate(c.target, values)
+	if len(exps) > 0 {
+		update = update.Where(exps...)
+		d.writeBack = nil
+	}
+	d.dataset = d.dataset.OnConflict(update)
+	d.conflictTarget = ""
+	return d
+}
+
+// withConflictTarget adds the target to ON CONFLICT DO NOTHING,
+// goqu renders DO NOTHING without it.
+func withConflictTarget(query, target string) string {
+	i := strings.LastIndex(query, conflictDoNothing)
+	if target == "" || i < 0 {
+		return query
+	}
+	return query[:i] + " ON CONFLICT " + wrapConflictTarget(target) + " DO NOTHING" + query[i+len(conflictDoNothing):]
+}
+
+func wrapConflictTarget(target string) string {
+	if strings.HasPrefix(target, "ON CONSTRAINT") {
+		return target
+	}
+	return "(" + target + ")"
+}
@@ -3,7 +3,9 @@ package pgs
 import (
 	"context"
 	"fmt"
+	"github.com/doug-martin/goqu/v9"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"time"
 )
 
 type DbConfig struct {
@@ -13,11 +15,16 @@ type DbConfig struct {
 	Password  string
 	Name      string
 	PollCount int32
+	Prepared  bool
 }
 
 type DbClient struct {
-	Ctx  context.Context
-	Pool *pgxpool.Pool
+	Ctx      context.Context
+	Pool     *pgxpool.Pool
+	Prepared bool
+	// Now returns the time written to created_at, updated_at and deleted_at fields,
+	// the database now() is used if it is nil
+	Now func() time.Time
 }
 
 func (cli *DbClient) Connect(ctx context.Context, cfg DbConfig) error {
@@ -47,6 +54,21 @@ func (cli *DbClient) Connect(ctx context.Context, cfg DbConfig) error {
 
 	cli.Pool = db
 	cli.Ctx = ctx
+	cli.Prepared = cfg.Prepared
 
 	return nil
 }
+
+// prepared reports whether datasets should be built with placeholders
+// ($1..$n) and bound arguments instead of interpolated values.
+func (cli *DbClient) prepared() bool {
+	return cli != nil && cli.Prepared
+}
+
+// now returns the value written to timestamp fields, see Now.
+func (cli *DbClient) now() interface{} {
+	if cli != nil && cli.Now != nil {
+		return cli.Now()
+	}
+	return goqu.L("now()")
+}
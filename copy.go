@@ -0,0 +1,138 @@
+package pgs
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"reflect"
+)
+
+type copier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// CopyFrom loads rows with the COPY protocol and returns the number of copied rows.
+// source is a slice of model structs (or pointers to them), a channel of them
+// or an iterator func() (T, bool) returning false after the last row.
+// Without columns the writable fields of the model without the default option and the fk columns are copied.
+// The transaction of ctx (see DbClient.InTx) is used if there is one.
+func (m *Model) CopyFrom(ctx context.Context, source interface{}, columns ...fieldI) (int64, error) {
+	if len(columns) == 0 {
+		for _, field := range m.writableFields() {
+			if field.getModel() == m && field.getOptions().hasDefault {
+				continue
+			}
+			columns = append(columns, field)
+		}
+	}
+	var names []string
+	var paths [][]int
+	for _, field := range columns {
+		name, err := m.column(field)
+		if err != nil {
+			return 0, err
+		}
+		path, err := m.fieldPath(field)
+		if err != nil {
+			return 0, err
+		}
+		names = append(names, name)
+		paths = append(paths, path)
+	}
+
+	next, err := rowIterator(source)
+	if err != nil {
+		return 0, err
+	}
+	rowSrc := pgx.CopyFromFunc(func() ([]any, error) {
+		row, ok := next()
+		if !ok {
+			return nil, nil
+		}
+		for row.Kind() == reflect.Pointer || row.Kind() == reflect.Interface {
+			row = row.Elem()
+		}
+		// pgx calls the source on its own goroutine, so mismatched rows must not panic
+		if !row.IsValid() {
+			return nil, fmt.Errorf("copy: nil row for table %s", m.tableName)
+		}
+		if row.Type() != m.structType {
+			return nil, fmt.Errorf("copy: row of type %s does not match table %s", row.Type(), m.tableName)
+		}
+		if !row.CanAddr() {
+			addressable := reflect.New(row.Type()).Elem()
+			addressable.Set(row)
+			row = addressable
+		}
+		values := make([]any, len(paths))
+		for i, path := range paths {
+			values[i] = row.FieldByIndex(path).Addr().Interface().(fieldI).getValue()
+		}
+		return values, nil
+	})
+
+	var c copier = m.db.Pool
+	if tx, ok := TxFromContext(ctx); ok {
+		c = tx
+	}
+	return c.CopyFrom(ctx, pgx.Identifier{m.tableName}, names, rowSrc)
+}
+
+// copyColumns returns the fields of the model and the fk target fields of nested models.
+func (m *Model) copyColumns() []fieldI {
+	columns := append([]fieldI{}, m.fields...)
+	for _, fk := range m.fkModels {
+		for _, field := range fk.fields {
+			if field.getField() == fk.joiner.To {
+				columns = append(columns, field)
+			}
+		}
+	}
+	return columns
+}
+
+// fieldPath returns the struct field indexes of the field in the model struct.
+func (m *Model) fieldPath(field fieldI) ([]int, error) {
+	model := field.getModel()
+	for i, f := range model.fields {
+		if f != field {
+			continue
+		}
+		if model == m {
+			return []int{model.fieldIndex[i]}, nil
+		}
+		if model.parent == m {
+			return []int{model.index, model.fieldIndex[i]}, nil
+		}
+	}
+	return nil, fmt.Errorf("field %s does not belong to table %s", field.getField(), m.tableName)
+}
+
+// rowIterator returns a function yielding the rows of a slice, an array, a channel or an iterator.
+func rowIterator(source interface{}) (func() (reflect.Value, bool), error) {
+	value := reflect.ValueOf(source)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		i := 0
+		return func() (reflect.Value, bool) {
+			if i >= value.Len() {
+				return reflect.Value{}, false
+			}
+			i++
+			return value.Index(i - 1), true
+		}, nil
+	case reflect.Chan:
+		return func() (reflect.Value, bool) {
+			return value.Recv()
+		}, nil
+	case reflect.Func:
+		t := value.Type()
+		if t.NumIn() == 0 && t.NumOut() == 2 && t.Out(1).Kind() == reflect.Bool {
+			return func() (reflect.Value, bool) {
+				out := value.Call(nil)
+				return out[0], out[1].Bool()
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("copy: unsupported source %T, expected slice, channel or func() (T, bool)", source)
+}
@@ -1,10 +1,13 @@
 package pgs
 
 import (
+	"context"
+	"fmt"
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
+	"reflect"
 )
 
 type DeleteDataset struct {
@@ -12,27 +15,45 @@ type DeleteDataset struct {
 	dataset *goqu.DeleteDataset
 	err     error
 	tx      pgx.Tx
+	ctx     context.Context
+
+	requireWhere bool
+	hasWhere     bool
+	// soft deletes set the soft delete field instead of deleting, see HardDelete
+	soft bool
+	// hooks are the receivers of the delete hooks, see BeforeDelete
+	hooks []interface{}
 }
 
 func (d *DeleteDataset) Where(conditions ...Conditional) *DeleteDataset {
 	var exps []exp.Expression
 	for _, condition := range conditions {
 		cond, err := condition.Condition(true)
-		d.err = err
+		if err != nil {
+			d.setError(err)
+			continue
+		}
 		exps = append(exps, cond)
 	}
 	d.dataset = d.dataset.Where(exps...)
+	d.hasWhere = true
 	return d
 }
 
 func (d *DeleteDataset) Exec() error {
-	query, _, _ := d.dataset.ToSQL()
-	var err error
-	if d.tx != nil {
-		_, err = d.tx.Exec(d.model.db.Ctx, query)
-	} else {
-		_, err = d.model.db.Pool.Exec(d.model.db.Ctx, query)
+	return d.ExecContext(d.context())
+}
+
+func (d *DeleteDataset) ExecContext(ctx context.Context) error {
+	return d.withHooks(ctx, d.exec)
+}
+
+func (d *DeleteDataset) exec(ctx context.Context) error {
+	query, args, err := d.ToSQL()
+	if err != nil {
+		return err
 	}
+	_, err = d.querier(ctx).Exec(ctx, query, args...)
 	return err
 }
 
@@ -41,6 +62,18 @@ func (d *DeleteDataset) WithTx(tx pgx.Tx) *DeleteDataset {
 	return d
 }
 
+// WithContext sets the context used by Exec, Scan and ScanOne
+// instead of the one stored in DbClient.
+func (d *DeleteDataset) WithContext(ctx context.Context) *DeleteDataset {
+	d.ctx = ctx
+	return d
+}
+
+func (d *DeleteDataset) Prepared(prepared bool) *DeleteDataset {
+	d.dataset = d.dataset.Prepared(prepared)
+	return d
+}
+
 func (d *DeleteDataset) Returning(fields ...fieldI) *DeleteDataset {
 	var rValues []interface{}
 	for _, field := range fields {
@@ -51,28 +84,127 @@ func (d *DeleteDataset) Returning(fields ...fieldI) *DeleteDataset {
 }
 
 func (d *DeleteDataset) Scan(dst interface{}) error {
-	query, _, _ := d.dataset.ToSQL()
-	var err error
-	if d.tx != nil {
-		err = pgxscan.Select(d.model.db.Ctx, d.tx, dst, query)
-	} else {
-		err = pgxscan.Select(d.model.db.Ctx, d.model.db.Pool, dst, query)
+	return d.ScanContext(d.context(), dst)
+}
+
+func (d *DeleteDataset) ScanContext(ctx context.Context, dst interface{}) error {
+	return d.withHooks(ctx, func(ctx context.Context) error {
+		return d.scan(ctx, dst)
+	})
+}
+
+func (d *DeleteDataset) scan(ctx context.Context, dst interface{}) error {
+	query, args, err := d.ToSQL()
+	if err != nil {
+		return err
 	}
-	return err
+	return pgxscan.Select(ctx, d.querier(ctx), dst, query, args...)
 }
 
 func (d *DeleteDataset) ScanOne(dst interface{}) error {
-	query, _, _ := d.dataset.ToSQL()
-	var err error
-	if d.tx != nil {
-		err = pgxscan.Get(d.model.db.Ctx, d.tx, dst, query)
-	} else {
-		err = pgxscan.Get(d.model.db.Ctx, d.model.db.Pool, dst, query)
+	return d.ScanOneContext(d.context(), dst)
+}
+
+func (d *DeleteDataset) ScanOneContext(ctx context.Context, dst interface{}) error {
+	return d.withHooks(ctx, func(ctx context.Context) error {
+		return d.scanOne(ctx, dst)
+	})
+}
+
+func (d *DeleteDataset) scanOne(ctx context.Context, dst interface{}) error {
+	query, args, err := d.ToSQL()
+	if err != nil {
+		return err
 	}
-	return err
+	return pgxscan.Get(ctx, d.querier(ctx), dst, query, args...)
 }
 
 func (d *DeleteDataset) Query() string {
-	query, _, _ := d.dataset.ToSQL()
+	query, _, _ := d.ToSQL()
 	return query
 }
+
+// QueryArgs returns the query together with its bound arguments.
+// Arguments are empty unless the dataset is prepared.
+func (d *DeleteDataset) QueryArgs() (string, []interface{}) {
+	query, args, _ := d.ToSQL()
+	return query, args
+}
+
+// ToSQL returns the query, its bound arguments and the first error
+// recorded while building the dataset.
+func (d *DeleteDataset) ToSQL() (string, []interface{}, error) {
+	if d.err != nil {
+		return "", nil, d.err
+	}
+	if d.requireWhere && !d.hasWhere {
+		return "", nil, fmt.Errorf("model write on table %s requires Where", d.model.tableName)
+	}
+	if d.soft {
+		return bindSQL(d.softDelete())
+	}
+	return bindSQL(d.dataset)
+}
+
+// softDelete returns the update setting the soft delete field of the rows selected by the dataset.
+func (d *DeleteDataset) softDelete() *goqu.UpdateDataset {
+	deletedAt := d.model.deletedAt
+	clauses := d.dataset.GetClauses()
+	dataset := dialect.Update(d.model.tableName).
+		Set(goqu.Record{deletedAt.getField(): d.model.db.now()}).
+		Prepared(d.dataset.IsPrepared())
+	if clauses.Where() != nil {
+		dataset = dataset.Where(clauses.Where())
+	}
+	dataset = dataset.Where(deletedAt.getIdent().IsNull())
+	if clauses.Returning() != nil {
+		dataset = dataset.Returning(clauses.Returning())
+	}
+	return dataset
+}
+
+// wherePK selects the row by the primary key values of the model struct.
+func (d *DeleteDataset) wherePK(target reflect.Value) {
+	keys, err := d.model.structPK(target)
+	if err != nil {
+		d.setError(err)
+		return
+	}
+	conditions, err := d.model.pkConditions(keys)
+	if err != nil {
+		d.setError(err)
+		return
+	}
+	d.Where(conditions...)
+}
+
+// withHooks runs fn between the delete hooks, see BeforeDelete.
+func (d *DeleteDataset) withHooks(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withHooks(ctx, d.model.db, d.tx, deleteEvent, d.hooks, func(ctx context.Context) error {
+		return fn(ctx)
+	})
+}
+
+// setError keeps the first error, later ones are dropped.
+func (d *DeleteDataset) setError(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *DeleteDataset) context() context.Context {
+	if d.ctx != nil {
+		return d.ctx
+	}
+	return d.model.db.Ctx
+}
+
+func (d *DeleteDataset) querier(ctx context.Context) querier {
+	if d.tx != nil {
+		return d.tx
+	}
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return d.model.db.Pool
+}
@@ -1,27 +1,70 @@
 package pgs
 
 import (
+	"context"
+	"fmt"
 	"github.com/doug-martin/goqu/v9"
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
+	"reflect"
 )
 
+// maxBindParameters is the PostgreSQL limit of bound arguments in a statement.
+const maxBindParameters = 65535
+
 type InsertDataset struct {
 	model   *Model
 	dataset *goqu.InsertDataset
 	err     error
 	tx      pgx.Tx
+	ctx     context.Context
+
+	conflictTarget string
+	rows           []map[string]interface{}
+	batchSize      int
+	writeBack      *writeBack
+	// hooks are the receivers of the insert hooks, see BeforeInsert
+	hooks []interface{}
+	// refresh rereads the values of model writes after the before hooks
+	refresh func() error
+}
+
+type insertBatch struct {
+	query string
+	args  []interface{}
 }
 
 func (d *InsertDataset) Exec() error {
-	query, _, _ := d.dataset.ToSQL()
-	var err error
-	if d.tx != nil {
-		_, err = d.tx.Exec(d.model.db.Ctx, query)
-	} else {
-		_, err = d.model.db.Pool.Exec(d.model.db.Ctx, query)
+	return d.ExecContext(d.context())
+}
+
+func (d *InsertDataset) ExecContext(ctx context.Context) error {
+	return d.withHooks(ctx, d.exec)
+}
+
+func (d *InsertDataset) exec(ctx context.Context) error {
+	batches, err := d.batches()
+	if err != nil {
+		return err
 	}
-	return err
+	return d.run(ctx, len(batches), func(ctx context.Context, q querier) error {
+		offset := 0
+		for _, batch := range batches {
+			if d.writeBack != nil {
+				n, err := d.writeBack.query(ctx, q, offset, batch.query, batch.args...)
+				if err != nil {
+					return err
+				}
+				offset += n
+				continue
+			}
+			_, err := q.Exec(ctx, batch.query, batch.args...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func (d *InsertDataset) WithTx(tx pgx.Tx) *InsertDataset {
@@ -29,7 +72,21 @@ func (d *InsertDataset) WithTx(tx pgx.Tx) *InsertDataset {
 	return d
 }
 
+// WithContext sets the context used by Exec, Scan and ScanOne
+// instead of the one stored in DbClient.
+func (d *InsertDataset) WithContext(ctx context.Context) *InsertDataset {
+	d.ctx = ctx
+	return d
+}
+
+func (d *InsertDataset) Prepared(prepared bool) *InsertDataset {
+	d.dataset = d.dataset.Prepared(prepared)
+	return d
+}
+
+// Returning replaces the read back of InsertModel, use Scan to get the values.
 func (d *InsertDataset) Returning(fields ...fieldI) *InsertDataset {
+	d.writeBack = nil
 	var rValues []interface{}
 	for _, field := range fields {
 		rValues = append(rValues, field.getIdent())
@@ -39,28 +96,214 @@ func (d *InsertDataset) Returning(fields ...fieldI) *InsertDataset {
 }
 
 func (d *InsertDataset) Scan(dst interface{}) error {
-	query, _, _ := d.dataset.ToSQL()
-	var err error
-	if d.tx != nil {
-		err = pgxscan.Select(d.model.db.Ctx, d.tx, dst, query)
-	} else {
-		err = pgxscan.Select(d.model.db.Ctx, d.model.db.Pool, dst, query)
+	return d.ScanContext(d.context(), dst)
+}
+
+// ScanContext scans the returning rows into dst. In batches the rows of all
+// statements are appended to dst in the order of the records.
+func (d *InsertDataset) ScanContext(ctx context.Context, dst interface{}) error {
+	return d.withHooks(ctx, func(ctx context.Context) error {
+		return d.scan(ctx, dst)
+	})
+}
+
+func (d *InsertDataset) scan(ctx context.Context, dst interface{}) error {
+	batches, err := d.batches()
+	if err != nil {
+		return err
+	}
+	if len(batches) == 1 {
+		return pgxscan.Select(ctx, d.querier(ctx), dst, batches[0].query, batches[0].args...)
 	}
-	return err
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Pointer || dstValue.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("scan of batched insert: expected pointer to slice, got %T", dst)
+	}
+	return d.run(ctx, len(batches), func(ctx context.Context, q querier) error {
+		result := reflect.MakeSlice(dstValue.Elem().Type(), 0, len(d.rows))
+		for _, batch := range batches {
+			chunk := reflect.New(dstValue.Elem().Type())
+			err := pgxscan.Select(ctx, q, chunk.Interface(), batch.query, batch.args...)
+			if err != nil {
+				return err
+			}
+			result = reflect.AppendSlice(result, chunk.Elem())
+		}
+		dstValue.Elem().Set(result)
+		return nil
+	})
 }
 
 func (d *InsertDataset) ScanOne(dst interface{}) error {
-	query, _, _ := d.dataset.ToSQL()
-	var err error
-	if d.tx != nil {
-		err = pgxscan.Get(d.model.db.Ctx, d.tx, dst, query)
-	} else {
-		err = pgxscan.Get(d.model.db.Ctx, d.model.db.Pool, dst, query)
+	return d.ScanOneContext(d.context(), dst)
+}
+
+func (d *InsertDataset) ScanOneContext(ctx context.Context, dst interface{}) error {
+	return d.withHooks(ctx, func(ctx context.Context) error {
+		return d.scanOne(ctx, dst)
+	})
+}
+
+func (d *InsertDataset) scanOne(ctx context.Context, dst interface{}) error {
+	query, args, err := d.ToSQL()
+	if err != nil {
+		return err
 	}
-	return err
+	return pgxscan.Get(ctx, d.querier(ctx), dst, query, args...)
+}
+
+// Batch splits the records into statements of at most size records. All statements
+// run in one transaction: the transaction of the dataset if there is one, otherwise a new one.
+// In prepared mode records are split automatically to stay below the limit of bound arguments.
+func (d *InsertDataset) Batch(size int) *InsertDataset {
+	d.batchSize = size
+	return d
 }
 
 func (d *InsertDataset) Query() string {
-	query, _, _ := d.dataset.ToSQL()
+	query, _, _ := d.ToSQL()
 	return query
 }
+
+// QueryArgs returns the query together with its bound arguments.
+// Arguments are empty unless the dataset is prepared.
+func (d *InsertDataset) QueryArgs() (string, []interface{}) {
+	query, args, _ := d.ToSQL()
+	return query, args
+}
+
+// ToSQL returns the query, its bound arguments and the first error
+// recorded while building the dataset.
+func (d *InsertDataset) ToSQL() (string, []interface{}, error) {
+	if d.err != nil {
+		return "", nil, d.err
+	}
+	return d.toSQL(d.dataset)
+}
+
+func (d *InsertDataset) toSQL(dataset *goqu.InsertDataset) (string, []interface{}, error) {
+	query, args, err := bindSQL(dataset)
+	if err != nil {
+		return "", nil, err
+	}
+	return withConflictTarget(query, d.conflictTarget), args, nil
+}
+
+// batches splits the statement by records, see Batch.
+func (d *InsertDataset) batches() ([]insertBatch, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	size, err := d.batchRows()
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 || len(d.rows) <= size {
+		query, args, err := d.ToSQL()
+		if err != nil {
+			return nil, err
+		}
+		return []insertBatch{{query, args}}, nil
+	}
+	var batches []insertBatch
+	for start := 0; start < len(d.rows); start += size {
+		end := start + size
+		if end > len(d.rows) {
+			end = len(d.rows)
+		}
+		query, args, err := d.toSQL(d.dataset.ClearRows().Rows(d.rows[start:end]))
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, insertBatch{query, args})
+	}
+	return batches, nil
+}
+
+// batchRows returns the number of records per statement, 0 for a single statement.
+// Records can bind different numbers of arguments (e.g. DEFAULT or now() instead of a value),
+// so the statements are sized by the widest record.
+func (d *InsertDataset) batchRows() (int, error) {
+	size := d.batchSize
+	if !d.dataset.IsPrepared() || len(d.rows) < 2 {
+		return size, nil
+	}
+	widths := make([]int, len(d.rows))
+	for i := range d.rows {
+		_, args, err := d.toSQL(d.dataset.ClearRows().Rows(d.rows[i : i+1]))
+		if err != nil {
+			return 0, err
+		}
+		widths[i] = len(args)
+	}
+	_, two, err := d.toSQL(d.dataset.ClearRows().Rows(d.rows[:2]))
+	if err != nil {
+		return 0, err
+	}
+	// base is the number of arguments outside of the values, e.g. in ON CONFLICT
+	base := widths[0] + widths[1] - len(two)
+	perRow := 0
+	for _, width := range widths {
+		if width-base > perRow {
+			perRow = width - base
+		}
+	}
+	if perRow <= 0 {
+		return size, nil
+	}
+	limit := (maxBindParameters - base) / perRow
+	if size <= 0 && len(d.rows) <= limit {
+		return 0, nil
+	}
+	if size <= 0 || size > limit {
+		size = limit
+	}
+	return size, nil
+}
+
+// run calls fn in a transaction if there are several statements and the dataset has none.
+func (d *InsertDataset) run(ctx context.Context, statements int, fn func(ctx context.Context, q querier) error) error {
+	_, inTx := TxFromContext(ctx)
+	if statements < 2 || d.tx != nil || inTx {
+		return fn(ctx, d.querier(ctx))
+	}
+	return d.model.db.InTx(ctx, TxOptions{}, func(tx *Tx) error {
+		return fn(tx.Context(), tx)
+	})
+}
+
+// withHooks runs fn between the insert hooks, see BeforeInsert.
+func (d *InsertDataset) withHooks(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withHooks(ctx, d.model.db, d.tx, insertEvent, d.hooks, func(ctx context.Context) error {
+		if d.refresh != nil {
+			if err := d.refresh(); err != nil {
+				return err
+			}
+		}
+		return fn(ctx)
+	})
+}
+
+// setError keeps the first error, later ones are dropped.
+func (d *InsertDataset) setError(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *InsertDataset) context() context.Context {
+	if d.ctx != nil {
+		return d.ctx
+	}
+	return d.model.db.Ctx
+}
+
+func (d *InsertDataset) querier(ctx context.Context) querier {
+	if d.tx != nil {
+		return d.tx
+	}
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return d.model.db.Pool
+}
@@ -1,6 +1,8 @@
 package pgs
 
 import (
+	"context"
+	"fmt"
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/georgysavva/scany/v2/pgxscan"
@@ -11,29 +13,224 @@ type SelectDataset struct {
 	model        *Model
 	dataset      *goqu.SelectDataset
 	joinedTables map[string]bool
-	err          error
-	tx           pgx.Tx
+	joins        []*join
+	joinTypes    map[string]joinType
+	// selected are the fields of the select list, nil for other expressions
+	selected []fieldI
+	lock     exp.LockStrength
+	lockWait exp.WaitOption
+	lockOf   []exp.IdentifierExpression
+	deleted  deletedScope
+	preloads []preload
+	err      error
+	tx       pgx.Tx
+	ctx      context.Context
 }
 
 func (sd *SelectDataset) Where(conditions ...Conditional) *SelectDataset {
-	var exps []exp.Expression
+	exps := sd.conditions(conditions)
+	sd.dataset = sd.dataset.Where(exps...)
+	return sd
+}
+
+func (sd *SelectDataset) GroupBy(fields ...Ordered) *SelectDataset {
+	var groupBy []interface{}
+	for _, field := range fields {
+		sd.join(field.getJoiners())
+		groupBy = append(groupBy, field.getIdent())
+	}
+	sd.dataset = sd.dataset.GroupByAppend(groupBy...)
+	return sd
+}
+
+func (sd *SelectDataset) Having(conditions ...Conditional) *SelectDataset {
+	exps := sd.conditions(conditions)
+	sd.dataset = sd.dataset.Having(exps...)
+	return sd
+}
+
+// Window defines named windows (WINDOW name AS (...)) referenced by OverName.
+func (sd *SelectDataset) Window(windows ...WindowSpec) *SelectDataset {
+	for _, window := range windows {
+		sd.join(window.joiners)
+		definition, err := window.definition()
+		if err != nil {
+			sd.setError(err)
+			continue
+		}
+		sd.dataset = sd.dataset.WindowAppend(definition)
+	}
+	return sd
+}
+
+// InnerJoin joins the fk models with INNER JOIN instead of the join chosen by their fk tag.
+func (sd *SelectDataset) InnerJoin(models ...modelI) *SelectDataset {
+	return sd.setJoinType(innerJoin, models)
+}
+
+// LeftJoin joins the fk models with LEFT JOIN instead of the join chosen by their fk tag.
+func (sd *SelectDataset) LeftJoin(models ...modelI) *SelectDataset {
+	return sd.setJoinType(leftJoin, models)
+}
+
+func (sd *SelectDataset) setJoinType(kind joinType, models []modelI) *SelectDataset {
+	for _, model := range models {
+		m := model.getModel()
+		if m.joiner == nil {
+			sd.setError(fmt.Errorf("table %s is not a fk model, use JoinOn methods", m.tableName))
+			continue
+		}
+		sd.join(m.getJoiners())
+		sd.joinTypes[m.joiner.Name] = kind
+	}
+	return sd
+}
+
+// InnerJoinOn joins any initialized model on the conditions.
+func (sd *SelectDataset) InnerJoinOn(model modelI, conditions ...Conditional) *SelectDataset {
+	return sd.joinOn(innerJoin, goqu.T(model.getModel().tableName), conditions)
+}
+
+func (sd *SelectDataset) LeftJoinOn(model modelI, conditions ...Conditional) *SelectDataset {
+	return sd.joinOn(leftJoin, goqu.T(model.getModel().tableName), conditions)
+}
+
+func (sd *SelectDataset) RightJoinOn(model modelI, conditions ...Conditional) *SelectDataset {
+	return sd.joinOn(rightJoin, goqu.T(model.getModel().tableName), conditions)
+}
+
+func (sd *SelectDataset) FullJoinOn(model modelI, conditions ...Conditional) *SelectDataset {
+	return sd.joinOn(fullJoin, goqu.T(model.getModel().tableName), conditions)
+}
+
+func (sd *SelectDataset) CrossJoin(model modelI) *SelectDataset {
+	return sd.joinOn(crossJoin, goqu.T(model.getModel().tableName), nil)
+}
+
+// InnerJoinLateral joins the subquery as LATERAL (...) AS "as".
+func (sd *SelectDataset) InnerJoinLateral(ds *SelectDataset, as string, conditions ...Conditional) *SelectDataset {
+	return sd.joinOn(innerJoin, goqu.Lateral(ds.build().As(as)), conditions)
+}
+
+func (sd *SelectDataset) LeftJoinLateral(ds *SelectDataset, as string, conditions ...Conditional) *SelectDataset {
+	return sd.joinOn(leftJoin, goqu.Lateral(ds.build().As(as)), conditions)
+}
+
+func (sd *SelectDataset) CrossJoinLateral(ds *SelectDataset, as string) *SelectDataset {
+	return sd.joinOn(crossJoin, goqu.Lateral(ds.build().As(as)), nil)
+}
+
+func (sd *SelectDataset) joinOn(kind joinType, table exp.Expression, conditions []Conditional) *SelectDataset {
+	var on []exp.Expression
 	for _, condition := range conditions {
+		sd.join(condition.getJoiners())
 		cond, err := condition.Condition(false)
-		joiners := condition.getJoiners()
-		for _, joiner := range joiners {
-			if joiner == nil {
-				continue
-			}
-			_, ok := sd.joinedTables[joiner.Name]
-			if !ok {
-				sd.dataset = sd.dataset.LeftJoin(joiner.Table, joiner.On)
-				sd.joinedTables[joiner.Name] = true
-			}
+		if err != nil {
+			sd.setError(err)
+			continue
 		}
-		sd.err = err
-		exps = append(exps, cond)
+		on = append(on, cond)
+	}
+	sd.joins = append(sd.joins, &join{kind: kind, table: table, on: on})
+	return sd
+}
+
+// With adds the dataset as a common table expression. To query it with typed fields
+// define a model whose table tag is the name of the expression.
+func (sd *SelectDataset) With(name string, ds *SelectDataset) *SelectDataset {
+	sd.dataset = sd.dataset.With(name, ds.build())
+	return sd
+}
+
+// WithRecursive adds WITH RECURSIVE name AS (anchor UNION ALL recursive).
+// The recursive dataset refers to the expression through a model whose table tag is name.
+func (sd *SelectDataset) WithRecursive(name string, anchor, recursive *SelectDataset) *SelectDataset {
+	sd.dataset = sd.dataset.WithRecursive(name, anchor.build().UnionAll(recursive.build()))
+	return sd
+}
+
+// Union combines the results of both datasets. Where, OrderAsc, OrderDesc, Limit and Offset
+// called on the returned dataset apply to the combined result.
+func (sd *SelectDataset) Union(other *SelectDataset) *SelectDataset {
+	return sd.combine("UNION", other)
+}
+
+func (sd *SelectDataset) UnionAll(other *SelectDataset) *SelectDataset {
+	return sd.combine("UNION ALL", other)
+}
+
+func (sd *SelectDataset) Intersect(other *SelectDataset) *SelectDataset {
+	return sd.combine("INTERSECT", other)
+}
+
+func (sd *SelectDataset) Except(other *SelectDataset) *SelectDataset {
+	return sd.combine("EXCEPT", other)
+}
+
+// combine selects from (sd op other) aliased as the table of the model,
+// so fields of the model refer to the columns of the combined result.
+func (sd *SelectDataset) combine(op string, other *SelectDataset) *SelectDataset {
+	combined := goqu.L(fmt.Sprintf("(? %s ?)", op), sd.build(), other.build())
+	ds := &SelectDataset{
+		model:        sd.model,
+		dataset:      dialect.From(combined.As(sd.model.tableName)).Prepared(sd.dataset.IsPrepared()),
+		joinedTables: make(map[string]bool),
+		joinTypes:    make(map[string]joinType),
+		selected:     sd.selected,
+		deleted:      withDeleted,
+		err:          sd.err,
+		tx:           sd.tx,
+		ctx:          sd.ctx,
+	}
+	ds.setError(other.err)
+	if len(sd.selected) != len(other.selected) {
+		ds.setError(fmt.Errorf("%s requires the same number of columns, got %d and %d", op, len(sd.selected), len(other.selected)))
+	}
+	return ds
+}
+
+// ForUpdate locks the selected rows with FOR UPDATE. If models are passed,
+// only rows of their tables are locked (FOR UPDATE OF ...).
+func (sd *SelectDataset) ForUpdate(of ...modelI) *SelectDataset {
+	return sd.setLock(exp.ForUpdate, of)
+}
+
+func (sd *SelectDataset) ForNoKeyUpdate(of ...modelI) *SelectDataset {
+	return sd.setLock(exp.ForNoKeyUpdate, of)
+}
+
+func (sd *SelectDataset) ForShare(of ...modelI) *SelectDataset {
+	return sd.setLock(exp.ForShare, of)
+}
+
+func (sd *SelectDataset) ForKeyShare(of ...modelI) *SelectDataset {
+	return sd.setLock(exp.ForKeyShare, of)
+}
+
+// NoWait adds NOWAIT to the row lock.
+func (sd *SelectDataset) NoWait() *SelectDataset {
+	sd.lockWait = exp.NoWait
+	return sd
+}
+
+// SkipLocked adds SKIP LOCKED to the row lock.
+func (sd *SelectDataset) SkipLocked() *SelectDataset {
+	sd.lockWait = exp.SkipLocked
+	return sd
+}
+
+func (sd *SelectDataset) setLock(lock exp.LockStrength, of []modelI) *SelectDataset {
+	sd.lock = lock
+	sd.lockOf = nil
+	for _, model := range of {
+		m := model.getModel()
+		if m.joiner == nil {
+			sd.lockOf = append(sd.lockOf, goqu.I(m.tableName))
+			continue
+		}
+		sd.join(m.getJoiners())
+		sd.lockOf = append(sd.lockOf, goqu.I(m.joiner.Name))
 	}
-	sd.dataset = sd.dataset.Where(exps...)
 	return sd
 }
 
@@ -49,6 +246,7 @@ func (sd *SelectDataset) Offset(offset uint) *SelectDataset {
 
 func (sd *SelectDataset) OrderAsc(fields ...Ordered) *SelectDataset {
 	for _, field := range fields {
+		sd.join(field.getJoiners())
 		ident := field.getIdent()
 		sd.dataset = sd.dataset.OrderAppend(ident.Asc())
 	}
@@ -57,6 +255,7 @@ func (sd *SelectDataset) OrderAsc(fields ...Ordered) *SelectDataset {
 
 func (sd *SelectDataset) OrderDesc(fields ...Ordered) *SelectDataset {
 	for _, field := range fields {
+		sd.join(field.getJoiners())
 		ident := field.getIdent()
 		sd.dataset = sd.dataset.OrderAppend(ident.Desc())
 	}
@@ -64,41 +263,206 @@ func (sd *SelectDataset) OrderDesc(fields ...Ordered) *SelectDataset {
 }
 
 func (sd *SelectDataset) Scan(dst interface{}) error {
-	if sd.err != nil {
-		return sd.err
+	return sd.ScanContext(sd.context(), dst)
+}
+
+func (sd *SelectDataset) ScanContext(ctx context.Context, dst interface{}) error {
+	query, args, err := sd.ToSQL()
+	if err != nil {
+		return err
 	}
-	var q pgxscan.Querier
-	if sd.tx != nil {
-		q = sd.tx
-	} else {
-		q = sd.model.db.Pool
+	q, err := sd.querier(ctx)
+	if err != nil {
+		return err
+	}
+	if err := pgxscan.Select(ctx, q, dst, query, args...); err != nil {
+		return err
+	}
+	if err := sd.preload(ctx, dst); err != nil {
+		return err
 	}
-	query, _, _ := sd.dataset.ToSQL()
-	err := pgxscan.Select(sd.model.db.Ctx, q, dst, query)
-	return err
+	return afterFind(ctx, sd.tx, dst)
 }
 
 func (sd *SelectDataset) ScanOne(dst interface{}) error {
-	if sd.err != nil {
-		return sd.err
+	return sd.ScanOneContext(sd.context(), dst)
+}
+
+func (sd *SelectDataset) ScanOneContext(ctx context.Context, dst interface{}) error {
+	query, args, err := sd.ToSQL()
+	if err != nil {
+		return err
 	}
-	var q pgxscan.Querier
-	if sd.tx != nil {
-		q = sd.tx
-	} else {
-		q = sd.model.db.Pool
+	q, err := sd.querier(ctx)
+	if err != nil {
+		return err
+	}
+	if err := pgxscan.Get(ctx, q, dst, query, args...); err != nil {
+		return err
 	}
-	query, _, _ := sd.dataset.ToSQL()
-	err := pgxscan.Get(sd.model.db.Ctx, q, dst, query)
-	return err
+	if err := sd.preload(ctx, dst); err != nil {
+		return err
+	}
+	return afterFind(ctx, sd.tx, dst)
 }
 
 func (sd *SelectDataset) Query() string {
-	query, _, _ := sd.dataset.ToSQL()
+	query, _, _ := sd.ToSQL()
 	return query
 }
 
+// QueryArgs returns the query together with its bound arguments.
+// Arguments are empty unless the dataset is prepared.
+func (sd *SelectDataset) QueryArgs() (string, []interface{}) {
+	query, args, _ := sd.ToSQL()
+	return query, args
+}
+
+// ToSQL returns the query, its bound arguments and the first error
+// recorded while building the dataset.
+func (sd *SelectDataset) ToSQL() (string, []interface{}, error) {
+	if sd.err != nil {
+		return "", nil, sd.err
+	}
+	return bindSQL(sd.build())
+}
+
 func (sd *SelectDataset) WithTx(tx pgx.Tx) *SelectDataset {
 	sd.tx = tx
 	return sd
 }
+
+// WithContext sets the context used by Scan and ScanOne
+// instead of the one stored in DbClient.
+func (sd *SelectDataset) WithContext(ctx context.Context) *SelectDataset {
+	sd.ctx = ctx
+	return sd
+}
+
+func (sd *SelectDataset) Prepared(prepared bool) *SelectDataset {
+	sd.dataset = sd.dataset.Prepared(prepared)
+	return sd
+}
+
+// conditions builds the expressions of conditions and joins the tables they refer to.
+func (sd *SelectDataset) conditions(conditions []Conditional) []exp.Expression {
+	var exps []exp.Expression
+	for _, condition := range conditions {
+		sd.join(condition.getJoiners())
+		cond, err := condition.Condition(false)
+		if err != nil {
+			sd.setError(err)
+			continue
+		}
+		exps = append(exps, cond)
+	}
+	return exps
+}
+
+func (sd *SelectDataset) join(joiners []*joiner) {
+	for _, joiner := range joiners {
+		if joiner == nil {
+			continue
+		}
+		_, ok := sd.joinedTables[joiner.Name]
+		if !ok {
+			sd.joins = append(sd.joins, &join{table: joiner.Table, joiner: joiner})
+			sd.joinedTables[joiner.Name] = true
+		}
+	}
+}
+
+// joinType returns the join set by InnerJoin/LeftJoin, otherwise INNER JOIN
+// if the fk and all fks it is nested in are required, inner joined and not filtered
+// by soft delete (see fkJoinOn), so the join can not drop rows of the root table.
+func (sd *SelectDataset) joinType(joiner *joiner) joinType {
+	if kind, ok := sd.joinTypes[joiner.Name]; ok {
+		return kind
+	}
+	for m := joiner.model; m != nil && m.joiner != nil; m = m.parent {
+		kind, ok := sd.joinTypes[m.joiner.Name]
+		if (ok && kind != innerJoin) || (!ok && (!m.joiner.Required || sd.filtersDeleted(m))) {
+			return leftJoin
+		}
+	}
+	return innerJoin
+}
+
+// build applies the joins, the soft delete filter and the lock to the dataset.
+func (sd *SelectDataset) build() *goqu.SelectDataset {
+	dataset := sd.dataset
+	for _, j := range sd.joins {
+		kind := j.kind
+		var on exp.JoinCondition
+		if j.joiner != nil {
+			kind = sd.joinType(j.joiner)
+			on = sd.fkJoinOn(j.joiner)
+		}
+		dataset = j.apply(dataset, kind, on)
+	}
+	if deleted := sd.deletedCondition(); deleted != nil {
+		dataset = dataset.Where(deleted)
+	}
+	if sd.lock != exp.ForNolock {
+		switch sd.lock {
+		case exp.ForUpdate:
+			dataset = dataset.ForUpdate(sd.lockWait, sd.lockOf...)
+		case exp.ForNoKeyUpdate:
+			dataset = dataset.ForNoKeyUpdate(sd.lockWait, sd.lockOf...)
+		case exp.ForShare:
+			dataset = dataset.ForShare(sd.lockWait, sd.lockOf...)
+		case exp.ForKeyShare:
+			dataset = dataset.ForKeyShare(sd.lockWait, sd.lockOf...)
+		}
+	}
+	if sd.err != nil {
+		dataset = dataset.SetError(sd.err)
+	}
+	return dataset
+}
+
+// selectedNames returns the aliases of the select list, empty for columns without alias.
+func (sd *SelectDataset) selectedNames() []string {
+	var names []string
+	selected := sd.dataset.GetClauses().Select()
+	if selected == nil {
+		return nil
+	}
+	for _, column := range selected.Columns() {
+		var name string
+		if aliased, ok := column.(exp.AliasedExpression); ok {
+			if col, ok := aliased.GetAs().GetCol().(string); ok {
+				name = col
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// setError keeps the first error, later ones are dropped.
+func (sd *SelectDataset) setError(err error) {
+	if sd.err == nil {
+		sd.err = err
+	}
+}
+
+func (sd *SelectDataset) context() context.Context {
+	if sd.ctx != nil {
+		return sd.ctx
+	}
+	return sd.model.db.Ctx
+}
+
+func (sd *SelectDataset) querier(ctx context.Context) (querier, error) {
+	if sd.tx != nil {
+		return sd.tx, nil
+	}
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx, nil
+	}
+	if sd.lock != exp.ForNolock {
+		return nil, fmt.Errorf("row locking requires a transaction, use WithTx or InTx")
+	}
+	return sd.model.db.Pool, nil
+}
@@ -1,10 +1,14 @@
 package pgs
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgx/v5"
+	"reflect"
 )
 
 type UpdateDataset struct {
@@ -12,36 +16,103 @@ type UpdateDataset struct {
 	dataset *goqu.UpdateDataset
 	err     error
 	tx      pgx.Tx
+	ctx     context.Context
+
+	requireWhere bool
+	hasWhere     bool
+	writeBack    *writeBack
+	// hooks are the receivers of the update hooks, see BeforeUpdate
+	hooks []interface{}
+	// refresh rereads the values of model writes after the before hooks
+	refresh func() error
+	// dirty struct fields written by Save, reset after Exec
+	dirty []fieldI
+	// noop is set by Save when no field is dirty
+	noop bool
+	// versioned model writes return ErrStaleObject when no row is updated
+	versioned bool
 }
 
 func (d *UpdateDataset) Where(conditions ...Conditional) *UpdateDataset {
 	var exps []exp.Expression
 	for _, condition := range conditions {
 		cond, err := condition.Condition(true)
-		d.err = err
+		if err != nil {
+			d.setError(err)
+			continue
+		}
 		exps = append(exps, cond)
 	}
 	d.dataset = d.dataset.Where(exps...)
+	d.hasWhere = true
 	return d
 }
 
 func (d *UpdateDataset) Exec() error {
-	query, _, _ := d.dataset.ToSQL()
-	var err error
-	if d.tx != nil {
-		_, err = d.tx.Exec(d.model.db.Ctx, query)
-	} else {
-		_, err = d.model.db.Pool.Exec(d.model.db.Ctx, query)
+	return d.ExecContext(d.context())
+}
+
+func (d *UpdateDataset) ExecContext(ctx context.Context) error {
+	if d.err == nil && d.noop {
+		return nil
+	}
+	if err := d.withHooks(ctx, d.exec); err != nil {
+		return err
+	}
+	for _, field := range d.dirty {
+		field.Reset()
+	}
+	return nil
+}
+
+func (d *UpdateDataset) exec(ctx context.Context) error {
+	query, args, err := d.ToSQL()
+	if err != nil {
+		return err
+	}
+	if d.writeBack != nil {
+		var n int
+		n, err = d.writeBack.query(ctx, d.querier(ctx), 0, query, args...)
+		if err == nil && n == 0 {
+			err = d.stale()
+		}
+		return err
+	}
+	tag, err := d.querier(ctx).Exec(ctx, query, args...)
+	if err == nil && tag.RowsAffected() == 0 {
+		err = d.stale()
 	}
 	return err
 }
 
+// stale returns ErrStaleObject for versioned model writes, called when no row is updated.
+func (d *UpdateDataset) stale() error {
+	if !d.versioned {
+		return nil
+	}
+	return fmt.Errorf("%w: table %s", ErrStaleObject, d.model.tableName)
+}
+
 func (d *UpdateDataset) WithTx(tx pgx.Tx) *UpdateDataset {
 	d.tx = tx
 	return d
 }
 
+// WithContext sets the context used by Exec, Scan and ScanOne
+// instead of the one stored in DbClient.
+func (d *UpdateDataset) WithContext(ctx context.Context) *UpdateDataset {
+	d.ctx = ctx
+	return d
+}
+
+func (d *UpdateDataset) Prepared(prepared bool) *UpdateDataset {
+	d.dataset = d.dataset.Prepared(prepared)
+	return d
+}
+
+// Returning replaces the read back of UpdateModel, use Scan to get the values.
 func (d *UpdateDataset) Returning(fields ...fieldI) *UpdateDataset {
+	d.writeBack = nil
 	var rValues []interface{}
 	for _, field := range fields {
 		rValues = append(rValues, field.getIdent())
@@ -51,28 +122,142 @@ func (d *UpdateDataset) Returning(fields ...fieldI) *UpdateDataset {
 }
 
 func (d *UpdateDataset) Scan(dst interface{}) error {
-	query, _, _ := d.dataset.ToSQL()
-	var err error
-	if d.tx != nil {
-		err = pgxscan.Select(d.model.db.Ctx, d.tx, dst, query)
-	} else {
-		err = pgxscan.Select(d.model.db.Ctx, d.model.db.Pool, dst, query)
+	return d.ScanContext(d.context(), dst)
+}
+
+func (d *UpdateDataset) ScanContext(ctx context.Context, dst interface{}) error {
+	return d.withHooks(ctx, func(ctx context.Context) error {
+		return d.scan(ctx, dst)
+	})
+}
+
+func (d *UpdateDataset) scan(ctx context.Context, dst interface{}) error {
+	query, args, err := d.ToSQL()
+	if err != nil {
+		return err
 	}
-	return err
+	rows, err := d.querier(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if err := pgxscan.ScanAll(dst, rows); err != nil {
+		return err
+	}
+	if rows.CommandTag().RowsAffected() == 0 {
+		return d.stale()
+	}
+	return nil
 }
 
 func (d *UpdateDataset) ScanOne(dst interface{}) error {
-	query, _, _ := d.dataset.ToSQL()
-	var err error
-	if d.tx != nil {
-		err = pgxscan.Get(d.model.db.Ctx, d.tx, dst, query)
-	} else {
-		err = pgxscan.Get(d.model.db.Ctx, d.model.db.Pool, dst, query)
+	return d.ScanOneContext(d.context(), dst)
+}
+
+func (d *UpdateDataset) ScanOneContext(ctx context.Context, dst interface{}) error {
+	return d.withHooks(ctx, func(ctx context.Context) error {
+		return d.scanOne(ctx, dst)
+	})
+}
+
+func (d *UpdateDataset) scanOne(ctx context.Context, dst interface{}) error {
+	query, args, err := d.ToSQL()
+	if err != nil {
+		return err
+	}
+	err = pgxscan.Get(ctx, d.querier(ctx), dst, query, args...)
+	if errors.Is(err, pgx.ErrNoRows) && d.versioned {
+		return d.stale()
 	}
 	return err
 }
 
 func (d *UpdateDataset) Query() string {
-	query, _, _ := d.dataset.ToSQL()
+	query, _, _ := d.ToSQL()
 	return query
 }
+
+// QueryArgs returns the query together with its bound arguments.
+// Arguments are empty unless the dataset is prepared.
+func (d *UpdateDataset) QueryArgs() (string, []interface{}) {
+	query, args, _ := d.ToSQL()
+	return query, args
+}
+
+// ToSQL returns the query, its bound arguments and the first error
+// recorded while building the dataset.
+func (d *UpdateDataset) ToSQL() (string, []interface{}, error) {
+	if d.err != nil {
+		return "", nil, d.err
+	}
+	if d.requireWhere && !d.hasWhere {
+		return "", nil, fmt.Errorf("model write on table %s requires Where", d.model.tableName)
+	}
+	return bindSQL(d.dataset)
+}
+
+// wherePK selects the row by the primary key values of the model struct.
+func (d *UpdateDataset) wherePK(target reflect.Value) {
+	keys, err := d.model.structPK(target)
+	if err != nil {
+		d.setError(err)
+		return
+	}
+	conditions, err := d.model.pkConditions(keys)
+	if err != nil {
+		d.setError(err)
+		return
+	}
+	d.Where(conditions...)
+}
+
+// whereVersion selects the row only if its version is the one of the model struct.
+// Unlike Where it does not satisfy requireWhere.
+func (d *UpdateDataset) whereVersion(target reflect.Value) {
+	version, err := d.model.structField(target, d.model.version)
+	if err != nil {
+		d.setError(err)
+		return
+	}
+	if isNull(version.getValue()) {
+		d.setError(fmt.Errorf("version field %s is NULL", d.model.version.getField()))
+		return
+	}
+	d.dataset = d.dataset.Where(d.model.version.getIdent().Eq(bind(version.getValue())))
+	d.versioned = true
+}
+
+// withHooks runs fn between the update hooks, see BeforeUpdate.
+func (d *UpdateDataset) withHooks(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withHooks(ctx, d.model.db, d.tx, updateEvent, d.hooks, func(ctx context.Context) error {
+		if d.refresh != nil {
+			if err := d.refresh(); err != nil {
+				return err
+			}
+		}
+		return fn(ctx)
+	})
+}
+
+// setError keeps the first error, later ones are dropped.
+func (d *UpdateDataset) setError(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *UpdateDataset) context() context.Context {
+	if d.ctx != nil {
+		return d.ctx
+	}
+	return d.model.db.Ctx
+}
+
+func (d *UpdateDataset) querier(ctx context.Context) querier {
+	if d.tx != nil {
+		return d.tx
+	}
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return d.model.db.Pool
+}
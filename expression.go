@@ -3,6 +3,7 @@ package pgs
 import (
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
+	"strings"
 )
 
 type CountExpression struct {
@@ -46,7 +47,7 @@ func L(sql string, values ...interface{}) LiteralExpression {
 			j = append(j, field.getJoiners()...)
 			args = append(args, field.getIdent())
 		} else {
-			args = append(args, value)
+			args = append(args, bind(value))
 		}
 
 	}
@@ -75,3 +76,157 @@ func (l LiteralExpression) getJoiners() []*joiner {
 func (l LiteralExpression) Condition(inUpdate bool) (goqu.Expression, error) {
 	return l.expression.Expression(), nil
 }
+
+type AggregateExpression struct {
+	name     string
+	distinct bool
+	field    fieldI
+	extra    []interface{}
+	order    []exp.OrderedExpression
+	filter   []exp.Expression
+	as       string
+	joiners  []*joiner
+	err      error
+}
+
+func newAggregate(name string, field fieldI, extra ...interface{}) AggregateExpression {
+	return AggregateExpression{
+		name:    name,
+		field:   field,
+		extra:   extra,
+		joiners: field.getJoiners(),
+	}
+}
+
+func Sum(field fieldI) AggregateExpression {
+	return newAggregate("SUM", field)
+}
+
+func Avg(field fieldI) AggregateExpression {
+	return newAggregate("AVG", field)
+}
+
+func Min(field fieldI) AggregateExpression {
+	return newAggregate("MIN", field)
+}
+
+func Max(field fieldI) AggregateExpression {
+	return newAggregate("MAX", field)
+}
+
+func CountDistinct(field fieldI) AggregateExpression {
+	a := newAggregate("COUNT", field)
+	a.distinct = true
+	return a
+}
+
+func ArrayAgg(field fieldI) AggregateExpression {
+	return newAggregate("ARRAY_AGG", field)
+}
+
+func StringAgg(field fieldI, separator string) AggregateExpression {
+	return newAggregate("STRING_AGG", field, separator)
+}
+
+func JsonAgg(field fieldI) AggregateExpression {
+	return newAggregate("JSON_AGG", field)
+}
+
+func BoolAnd(field fieldI) AggregateExpression {
+	return newAggregate("BOOL_AND", field)
+}
+
+func BoolOr(field fieldI) AggregateExpression {
+	return newAggregate("BOOL_OR", field)
+}
+
+func (a AggregateExpression) As(as string) AggregateExpression {
+	a.as = as
+	return a
+}
+
+// Filter adds FILTER (WHERE ...) to the aggregate, conditions are joined with AND.
+func (a AggregateExpression) Filter(conditions ...Conditional) AggregateExpression {
+	a.filter = a.filter[:len(a.filter):len(a.filter)]
+	a.joiners = a.joiners[:len(a.joiners):len(a.joiners)]
+	for _, condition := range conditions {
+		a.joiners = append(a.joiners, condition.getJoiners()...)
+		cond, err := condition.Condition(false)
+		if err != nil {
+			if a.err == nil {
+				a.err = err
+			}
+			continue
+		}
+		a.filter = append(a.filter, cond)
+	}
+	return a
+}
+
+// OrderAsc adds ORDER BY ... ASC inside the aggregate.
+func (a AggregateExpression) OrderAsc(fields ...Ordered) AggregateExpression {
+	return a.orderAppend(fields, func(ident exp.IdentifierExpression) exp.OrderedExpression {
+		return ident.Asc()
+	})
+}
+
+// OrderDesc adds ORDER BY ... DESC inside the aggregate.
+func (a AggregateExpression) OrderDesc(fields ...Ordered) AggregateExpression {
+	return a.orderAppend(fields, func(ident exp.IdentifierExpression) exp.OrderedExpression {
+		return ident.Desc()
+	})
+}
+
+func (a AggregateExpression) orderAppend(fields []Ordered, order func(exp.IdentifierExpression) exp.OrderedExpression) AggregateExpression {
+	a.order = a.order[:len(a.order):len(a.order)]
+	a.joiners = a.joiners[:len(a.joiners):len(a.joiners)]
+	for _, field := range fields {
+		a.joiners = append(a.joiners, field.getJoiners()...)
+		a.order = append(a.order, order(field.getIdent()))
+	}
+	return a
+}
+
+func (a AggregateExpression) expression() exp.LiteralExpression {
+	var sql strings.Builder
+	args := []interface{}{a.field.getIdent()}
+	sql.WriteString(a.name)
+	sql.WriteString("(")
+	if a.distinct {
+		sql.WriteString("DISTINCT ")
+	}
+	sql.WriteString("?")
+	for _, extra := range a.extra {
+		sql.WriteString(", ?")
+		args = append(args, extra)
+	}
+	for i, order := range a.order {
+		if i == 0 {
+			sql.WriteString(" ORDER BY ?")
+		} else {
+			sql.WriteString(", ?")
+		}
+		args = append(args, order)
+	}
+	sql.WriteString(")")
+	if len(a.filter) > 0 {
+		sql.WriteString(" FILTER (WHERE ?)")
+		args = append(args, goqu.And(a.filter...))
+	}
+	return goqu.L(sql.String(), args...)
+}
+
+func (a AggregateExpression) getSelectors() []interface{} {
+	if a.as != "" {
+		return []interface{}{a.expression().As(a.as)}
+	}
+	return []interface{}{a.expression()}
+}
+
+func (a AggregateExpression) getJoiners() []*joiner {
+	return a.joiners
+}
+
+func (a AggregateExpression) getError() error {
+	return a.err
+}
@@ -9,10 +9,50 @@ import (
 )
 
 type Field[T any] struct {
-	Value T
-	field string
-	as    string
-	model *Model
+	Value   T
+	field   string
+	as      string
+	model   *Model
+	options fieldOptions
+	dirty   bool
+}
+
+type fieldOptions struct {
+	// readonly fields are never written by model writes and are read back with RETURNING
+	readonly bool
+	// hasDefault fields are written as DEFAULT when NULL on insert and are read back with RETURNING
+	hasDefault bool
+}
+
+func parseFieldOptions(values []string) (fieldOptions, error) {
+	var options fieldOptions
+	for _, value := range values {
+		switch value {
+		case "readonly":
+			options.readonly = true
+		case "default":
+			options.hasDefault = true
+		default:
+			return options, fmt.Errorf("unknown db tag option %s", value)
+		}
+	}
+	return options, nil
+}
+
+// Set changes the value and marks the field as dirty, see Model.Save.
+func (f *Field[T]) Set(value T) {
+	f.Value = value
+	f.dirty = true
+}
+
+// IsDirty reports whether the field was changed with Set since it was loaded or reset.
+func (f *Field[T]) IsDirty() bool {
+	return f.dirty
+}
+
+// Reset marks the field as clean.
+func (f *Field[T]) Reset() {
+	f.dirty = false
 }
 
 func (f *Field[T]) getField() string {
@@ -23,6 +63,18 @@ func (f *Field[T]) getModel() *Model {
 	return f.model
 }
 
+func (f *Field[T]) getValue() interface{} {
+	return f.Value
+}
+
+func (f *Field[T]) getValuePtr() interface{} {
+	return &f.Value
+}
+
+func (f *Field[T]) getOptions() fieldOptions {
+	return f.options
+}
+
 func (f *Field[T]) getSelector() exp.AliasedExpression {
 	var selector exp.IdentifierExpression
 	if f.model.prefix == "" {
@@ -63,9 +115,10 @@ func (f *Field[T]) getJoiners() []*joiner {
 	return f.model.getJoiners()
 }
 
-func (f *Field[T]) init(model *Model, field string) {
+func (f *Field[T]) init(model *Model, field string, options fieldOptions) {
 	f.model = model
 	f.field = field
+	f.options = options
 }
 
 func (f Field[T]) MarshalJSON() ([]byte, error) {
@@ -95,6 +148,7 @@ func (f *Field[T]) As(as string) *Field[T] {
 }
 
 func (f *Field[T]) Scan(src interface{}) error {
+	f.dirty = false
 	v := interface{}(&f.Value)
 	if pgValue, ok := v.(*pgtype.Int2); ok {
 		return pgValue.Scan(src)
@@ -175,7 +229,7 @@ func (f *Field[T]) In(value interface{}) Condition {
 		return Condition{
 			Field:   f,
 			Op:      opEq,
-			Value:   ds.dataset,
+			Value:   ds.build(),
 			joiners: f.getJoiners(),
 		}
 	}
@@ -194,7 +248,7 @@ func (f *Field[T]) NotIn(value interface{}) Condition {
 		return Condition{
 			Field:   f,
 			Op:      opNotEq,
-			Value:   ds.dataset,
+			Value:   ds.build(),
 			joiners: f.getJoiners(),
 		}
 	}
@@ -209,7 +263,7 @@ func (f *Field[T]) NotIn(value interface{}) Condition {
 func (f *Field[T]) Eq(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -222,7 +276,7 @@ func (f *Field[T]) Eq(value interface{}) Condition {
 func (f *Field[T]) NotEq(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -235,7 +289,7 @@ func (f *Field[T]) NotEq(value interface{}) Condition {
 func (f *Field[T]) Like(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -248,7 +302,7 @@ func (f *Field[T]) Like(value interface{}) Condition {
 func (f *Field[T]) NotLike(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -261,7 +315,7 @@ func (f *Field[T]) NotLike(value interface{}) Condition {
 func (f *Field[T]) Regex(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -274,7 +328,7 @@ func (f *Field[T]) Regex(value interface{}) Condition {
 func (f *Field[T]) RegexI(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -287,7 +341,7 @@ func (f *Field[T]) RegexI(value interface{}) Condition {
 func (f *Field[T]) NotRegex(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -300,7 +354,7 @@ func (f *Field[T]) NotRegex(value interface{}) Condition {
 func (f *Field[T]) NotRegexI(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -313,7 +367,7 @@ func (f *Field[T]) NotRegexI(value interface{}) Condition {
 func (f *Field[T]) Lt(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -326,7 +380,7 @@ func (f *Field[T]) Lt(value interface{}) Condition {
 func (f *Field[T]) Lte(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -339,7 +393,7 @@ func (f *Field[T]) Lte(value interface{}) Condition {
 func (f *Field[T]) Gt(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -352,7 +406,7 @@ func (f *Field[T]) Gt(value interface{}) Condition {
 func (f *Field[T]) Gte(value interface{}) Condition {
 	ds, ok := value.(*SelectDataset)
 	if ok {
-		value = ds.dataset
+		value = ds.build()
 	}
 	return Condition{
 		Field:   f,
@@ -0,0 +1,229 @@
+package pgs
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jackc/pgx/v5"
+	"reflect"
+)
+
+// Model structs implement hook interfaces to run code around the statements of their model.
+// Hooks of writes run in the transaction of the statement: the one of the dataset if there is one,
+// otherwise a new one. A hook error rolls the statement back and is returned by Exec or Scan.
+// ctx carries the transaction, see TxFromContext.
+//
+// Write hooks are called on the structs passed to model writes (InsertModel, UpdateModel, Save, DeleteModel).
+// Writes of records call them on a new struct of the model per record holding the record values, see Model.Insert.
+type BeforeInsert interface {
+	BeforeInsert(ctx context.Context, tx *Tx) error
+}
+
+type AfterInsert interface {
+	AfterInsert(ctx context.Context, tx *Tx) error
+}
+
+type BeforeUpdate interface {
+	BeforeUpdate(ctx context.Context, tx *Tx) error
+}
+
+type AfterUpdate interface {
+	AfterUpdate(ctx context.Context, tx *Tx) error
+}
+
+type BeforeDelete interface {
+	BeforeDelete(ctx context.Context, tx *Tx) error
+}
+
+type AfterDelete interface {
+	AfterDelete(ctx context.Context, tx *Tx) error
+}
+
+// AfterFind is called for every struct scanned by a select, tx is nil outside a transaction.
+type AfterFind interface {
+	AfterFind(ctx context.Context, tx *Tx) error
+}
+
+type hookEvent int
+
+const (
+	insertEvent hookEvent = iota
+	updateEvent
+	deleteEvent
+)
+
+// hook returns the before or after hook of receiver for the event, nil if it has none.
+func (e hookEvent) hook(receiver interface{}, after bool) func(ctx context.Context, tx *Tx) error {
+	switch e {
+	case insertEvent:
+		if h, ok := receiver.(BeforeInsert); ok && !after {
+			return h.BeforeInsert
+		}
+		if h, ok := receiver.(AfterInsert); ok && after {
+			return h.AfterInsert
+		}
+	case updateEvent:
+		if h, ok := receiver.(BeforeUpdate); ok && !after {
+			return h.BeforeUpdate
+		}
+		if h, ok := receiver.(AfterUpdate); ok && after {
+			return h.AfterUpdate
+		}
+	case deleteEvent:
+		if h, ok := receiver.(BeforeDelete); ok && !after {
+			return h.BeforeDelete
+		}
+		if h, ok := receiver.(AfterDelete); ok && after {
+			return h.AfterDelete
+		}
+	}
+	return nil
+}
+
+func (e hookEvent) hooked(receivers []interface{}) bool {
+	for _, receiver := range receivers {
+		if e.hook(receiver, false) != nil || e.hook(receiver, true) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// hooked reports whether the structs of the model implement a hook of the event.
+func (m *Model) hooked(event hookEvent) bool {
+	return event.hooked([]interface{}{reflect.New(m.structType).Interface()})
+}
+
+// recordReceiver is the receiver of the hooks of a record write: a new struct of the model
+// holding the record values that fit its fields.
+type recordReceiver struct {
+	target reflect.Value
+	fields []fieldI
+	// values are the values of fields before the hooks
+	values []interface{}
+}
+
+func (m *Model) newRecordReceiver(record Record) *recordReceiver {
+	r := &recordReceiver{target: reflect.New(m.structType).Elem()}
+	for field, value := range record {
+		targetField, err := m.structField(r.target, field)
+		if err != nil {
+			continue
+		}
+		ptr := reflect.ValueOf(targetField.getValuePtr()).Elem()
+		rValue := reflect.ValueOf(value)
+		if rValue.IsValid() && rValue.Type().AssignableTo(ptr.Type()) {
+			ptr.Set(rValue)
+		} else if !scannable(value) || targetField.Scan(driverValue(value)) != nil {
+			continue
+		}
+		r.fields = append(r.fields, field)
+		r.values = append(r.values, targetField.getValue())
+	}
+	return r
+}
+
+// scannable reports whether a record value is a value rather than a field or an expression.
+func scannable(value interface{}) bool {
+	switch value.(type) {
+	case fieldI, exp.Expression:
+		return false
+	}
+	return true
+}
+
+// driverValue converts value to a driver value for Scan, e.g. an int to int64.
+func driverValue(value interface{}) interface{} {
+	if converted, err := driver.DefaultParameterConverter.ConvertValue(value); err == nil {
+		return converted
+	}
+	return value
+}
+
+func (r *recordReceiver) model() interface{} {
+	return r.target.Addr().Interface()
+}
+
+// write writes the fields changed by the before hooks into the values of the record write.
+func (r *recordReceiver) write(m *Model, values map[string]interface{}) {
+	for i, field := range r.fields {
+		targetField, _ := m.structField(r.target, field)
+		if value := targetField.getValue(); !reflect.DeepEqual(value, r.values[i]) {
+			column, _ := m.column(field)
+			values[column] = bind(value)
+		}
+	}
+}
+
+// withHooks runs fn between the hooks of receivers in a transaction, see BeforeInsert.
+// Without hooks fn runs as is.
+func withHooks(ctx context.Context, db *DbClient, tx pgx.Tx, event hookEvent, receivers []interface{}, fn func(ctx context.Context) error) error {
+	if !event.hooked(receivers) {
+		return fn(ctx)
+	}
+	run := func(tx *Tx) error {
+		ctx := tx.Context()
+		for _, receiver := range receivers {
+			if hook := event.hook(receiver, false); hook != nil {
+				if err := hook(ctx, tx); err != nil {
+					return err
+				}
+			}
+		}
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		for _, receiver := range receivers {
+			if hook := event.hook(receiver, true); hook != nil {
+				if err := hook(ctx, tx); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if tx != nil {
+		return run(wrapTx(ctx, tx))
+	}
+	if ctxTx, ok := TxFromContext(ctx); ok {
+		return run(ctxTx)
+	}
+	return db.InTx(ctx, TxOptions{}, run)
+}
+
+// afterFind calls AfterFind on the struct or on the elements of the slice dst points to.
+func afterFind(ctx context.Context, tx pgx.Tx, dst interface{}) error {
+	value := reflect.ValueOf(dst)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return nil
+	}
+	var hookTx *Tx
+	if tx != nil {
+		hookTx = wrapTx(ctx, tx)
+		ctx = hookTx.Context()
+	} else if ctxTx, ok := TxFromContext(ctx); ok {
+		hookTx = ctxTx
+	}
+	call := func(value reflect.Value) error {
+		if value.Kind() != reflect.Pointer {
+			value = value.Addr()
+		}
+		if value.IsNil() {
+			return nil
+		}
+		if h, ok := value.Interface().(AfterFind); ok {
+			return h.AfterFind(ctx, hookTx)
+		}
+		return nil
+	}
+	elem := value.Elem()
+	if elem.Kind() != reflect.Slice {
+		return call(value)
+	}
+	for i := 0; i < elem.Len(); i++ {
+		if err := call(elem.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
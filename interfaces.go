@@ -1,19 +1,30 @@
 package pgs
 
-import "github.com/doug-martin/goqu/v9/exp"
+import (
+	"context"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5/pgconn"
+)
 
 type modelI interface {
 	Init(db *DbClient, model interface{}) error
+	getModel() *Model
 }
 
 type fieldI interface {
 	MarshalJSON() ([]byte, error)
 	Scan(value interface{}) error
+	IsDirty() bool
+	Reset()
 
-	init(model *Model, field string)
+	init(model *Model, field string, options fieldOptions)
 	getSelector() exp.AliasedExpression
 	getField() string
 	getModel() *Model
+	getValue() interface{}
+	getValuePtr() interface{}
+	getOptions() fieldOptions
 
 	Selectable
 	Ordered
@@ -26,4 +37,16 @@ type Selectable interface {
 
 type Ordered interface {
 	getIdent() exp.IdentifierExpression
+	getJoiners() []*joiner
+}
+
+// erroneous is implemented by selectables that can fail to build.
+type erroneous interface {
+	getError() error
+}
+
+// querier is implemented by both *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	pgxscan.Querier
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
 }
@@ -0,0 +1,47 @@
+package pgs
+
+import (
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+type joinType int
+
+const (
+	leftJoin joinType = iota
+	innerJoin
+	rightJoin
+	fullJoin
+	crossJoin
+)
+
+type join struct {
+	kind   joinType
+	table  exp.Expression
+	on     []exp.Expression
+	joiner *joiner
+}
+
+// apply joins the table to dataset, fkOn is the join condition of the fk joiner.
+func (j *join) apply(dataset *goqu.SelectDataset, kind joinType, fkOn exp.JoinCondition) *goqu.SelectDataset {
+	var on exp.JoinCondition
+	if j.joiner != nil {
+		on = fkOn
+	} else if len(j.on) > 0 {
+		on = goqu.On(j.on...)
+	} else {
+		on = goqu.On(goqu.L("TRUE"))
+	}
+	switch kind {
+	case innerJoin:
+		return dataset.InnerJoin(j.table, on)
+	case rightJoin:
+		return dataset.RightJoin(j.table, on)
+	case fullJoin:
+		return dataset.FullJoin(j.table, on)
+	case crossJoin:
+		return dataset.CrossJoin(j.table)
+	default:
+		return dataset.LeftJoin(j.table, on)
+	}
+}
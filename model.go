@@ -1,8 +1,10 @@
 package pgs
 
 import (
+	"errors"
 	"fmt"
 	"github.com/doug-martin/goqu/v9"
+	_ "github.com/doug-martin/goqu/v9/dialect/postgres"
 	"github.com/doug-martin/goqu/v9/exp"
 	"reflect"
 	"strings"
@@ -10,6 +12,12 @@ import (
 
 const separator = "__"
 
+var dialect = goqu.Dialect("postgres")
+
+// ErrStaleObject is returned by model updates of a model with a version field
+// when the row was changed or deleted since the model struct was read.
+var ErrStaleObject = errors.New("stale object")
+
 type joiner struct {
 	ParentTable string
 	Name        string
@@ -17,14 +25,33 @@ type joiner struct {
 	To          string
 	Table       exp.AliasedExpression
 	On          exp.JoinCondition
+	Required    bool
+
+	model *Model
 }
 
 type Model struct {
 	db        *DbClient
+	self      interface{}
 	tableName string
 	fields    []fieldI
 	fkModels  []*Model
 
+	primaryKey []fieldI
+	relations  []*relation
+	// version is the field tagged with pgs:"version", see ErrStaleObject
+	version fieldI
+	// deletedAt is the field tagged with pgs:"deleted_at", see DeleteDataset.HardDelete
+	deletedAt fieldI
+	// createdAt and updatedAt are the fields tagged with pgs:"created_at" and pgs:"updated_at"
+	createdAt fieldI
+	updatedAt fieldI
+
+	// struct field indexes of fields and of the model in its parent
+	fieldIndex []int
+	index      int
+	structType reflect.Type
+
 	parent *Model
 	asName string
 	prefix string
@@ -33,8 +60,10 @@ type Model struct {
 
 func (m *Model) Init(db *DbClient, model interface{}) error {
 	m.db = db
+	m.self = model
 	rValue := reflect.ValueOf(model).Elem()
 	rType := rValue.Type()
+	m.structType = rType
 
 	// Check model field
 	for i := 0; i < rType.NumField(); i++ {
@@ -62,7 +91,17 @@ func (m *Model) Init(db *DbClient, model interface{}) error {
 			continue
 		}
 
-		dbTag := field.Tag.Get("db")
+		if relTag := field.Tag.Get("rel"); relTag != "" {
+			r, err := newRelation(field, i, relTag)
+			if err != nil {
+				return fmt.Errorf("error in init table: %w", err)
+			}
+			m.relations = append(m.relations, r)
+			continue
+		}
+
+		dbValues := strings.Split(field.Tag.Get("db"), ",")
+		dbTag := dbValues[0]
 		if dbTag == "-" {
 			continue
 		}
@@ -70,12 +109,23 @@ func (m *Model) Init(db *DbClient, model interface{}) error {
 		// Check Field[any]
 		dbField, ok := rValue.Field(i).Addr().Interface().(fieldI)
 		if ok {
+			options, err := parseFieldOptions(dbValues[1:])
+			if err != nil {
+				return fmt.Errorf("error in init table: field %s: %w", field.Name, err)
+			}
 			if dbTag == "" {
-				dbField.init(m, toSnakeCase(field.Name))
+				dbField.init(m, toSnakeCase(field.Name), options)
 			} else {
-				dbField.init(m, dbTag)
+				dbField.init(m, dbTag, options)
 			}
 			m.fields = append(m.fields, dbField)
+			m.fieldIndex = append(m.fieldIndex, i)
+			if pk, ok := field.Tag.Lookup("pk"); ok && pk != "false" {
+				m.primaryKey = append(m.primaryKey, dbField)
+			}
+			if err := m.setBehavior(field.Tag.Get("pgs"), dbField); err != nil {
+				return fmt.Errorf("error in init table: field %s: %w", field.Name, err)
+			}
 			continue
 		}
 
@@ -86,8 +136,18 @@ func (m *Model) Init(db *DbClient, model interface{}) error {
 				return fmt.Errorf("error in init table: not found db tag with fk %s", fkTag)
 			}
 			fkValues := strings.Split(fkTag, ",")
-			if len(fkValues) != 2 {
-				return fmt.Errorf("error in init table: uncorrect value in fk tag. Expected from_field,to_field. Goted: %s", fkTag)
+			if len(fkValues) != 2 && len(fkValues) != 3 {
+				return fmt.Errorf("error in init table: uncorrect value in fk tag. Expected from_field,to_field[,required|nullable]. Goted: %s", fkTag)
+			}
+			var required bool
+			if len(fkValues) == 3 {
+				switch fkValues[2] {
+				case "required":
+					required = true
+				case "nullable":
+				default:
+					return fmt.Errorf("error in init table: uncorrect fk option %s. Expected required or nullable", fkValues[2])
+				}
 			}
 
 			fkModelInterface, ok := rValue.Field(i).Addr().Interface().(modelI)
@@ -101,6 +161,7 @@ func (m *Model) Init(db *DbClient, model interface{}) error {
 			}
 			nestedModel.asName = dbTag
 			nestedModel.parent = m
+			nestedModel.index = i
 
 			err := fkModelInterface.Init(db, fkModelInterface)
 			if err != nil {
@@ -127,6 +188,8 @@ func (m *Model) Init(db *DbClient, model interface{}) error {
 			joiner.To = fkValues[1]
 			joiner.Name = tableAsName
 			joiner.ParentTable = m.tableName
+			joiner.Required = required
+			joiner.model = nestedModel
 			joiner.Table = goqu.T(nestedModel.tableName).As(tableAsName)
 			joiner.On = goqu.On(
 				goqu.Ex{
@@ -141,80 +204,486 @@ func (m *Model) Init(db *DbClient, model interface{}) error {
 		return fmt.Errorf("error in init table: unknown field %v", field.Name)
 	}
 
+	if err := m.resolveRelations(); err != nil {
+		return fmt.Errorf("error in init table: %w", err)
+	}
+	return nil
+}
+
+// setBehavior applies the pgs tag of field.
+func (m *Model) setBehavior(tag string, field fieldI) error {
+	switch tag {
+	case "":
+	case "version":
+		if m.version != nil {
+			return fmt.Errorf("table %s already has version field %s", m.tableName, m.version.getField())
+		}
+		if !isInteger(field.getValue()) {
+			return fmt.Errorf("version field must be pgtype.Int2, pgtype.Int4 or pgtype.Int8, got %T", field.getValue())
+		}
+		m.version = field
+	case "deleted_at":
+		if m.deletedAt != nil {
+			return fmt.Errorf("table %s already has soft delete field %s", m.tableName, m.deletedAt.getField())
+		}
+		m.deletedAt = field
+	case "created_at", "updated_at":
+		target := &m.createdAt
+		if tag == "updated_at" {
+			target = &m.updatedAt
+		}
+		if *target != nil {
+			return fmt.Errorf("table %s already has %s field %s", m.tableName, tag, (*target).getField())
+		}
+		if !isTimestamp(field.getValue()) {
+			return fmt.Errorf("%s field must be a timestamp, got %T", tag, field.getValue())
+		}
+		*target = field
+	default:
+		return fmt.Errorf("unknown pgs tag %s", tag)
+	}
 	return nil
 }
 
 func (m *Model) Select(fields ...Selectable) *SelectDataset {
-	dataset := goqu.From(m.tableName)
-	joinedTables := make(map[string]bool)
+	sd := &SelectDataset{
+		model:        m,
+		dataset:      dialect.From(m.tableName).Prepared(m.db.prepared()),
+		joinedTables: make(map[string]bool),
+		joinTypes:    make(map[string]joinType),
+	}
 
 	var selectFields []interface{}
 
 	if len(fields) == 0 {
 		selectFields = m.allSelectors()
-		joiners := m.allJoiners()
-		for _, joiner := range joiners {
-			if joiner != nil {
-				_, ok := joinedTables[joiner.Name]
-				if !ok {
-					dataset = dataset.LeftJoin(joiner.Table, joiner.On)
-					joinedTables[joiner.Name] = true
-				}
-			}
-		}
+		sd.selected = m.allSelectedFields()
+		sd.join(m.allJoiners())
 	}
 	for _, field := range fields {
-		selectFields = append(selectFields, field.getSelectors()...)
-		joiners := field.getJoiners()
-		for _, joiner := range joiners {
-			if joiner != nil {
-				_, ok := joinedTables[joiner.Name]
-				if !ok {
-					dataset = dataset.LeftJoin(joiner.Table, joiner.On)
-					joinedTables[joiner.Name] = true
-				}
+		if e, ok := field.(erroneous); ok {
+			if err := e.getError(); err != nil {
+				sd.setError(err)
 			}
 		}
+		selectFields = append(selectFields, field.getSelectors()...)
+		sd.selected = append(sd.selected, selectedFields(field)...)
+		sd.join(field.getJoiners())
 	}
 
-	dataset = dataset.Select(selectFields...)
-	return &SelectDataset{
-		model:        m,
-		dataset:      dataset,
-		joinedTables: joinedTables,
+	sd.dataset = sd.dataset.Select(selectFields...)
+	return sd
+}
+
+// selectedFields returns the fields selected by s in the order of its selectors, nil for expressions.
+func selectedFields(s Selectable) []fieldI {
+	if field, ok := s.(fieldI); ok {
+		return []fieldI{field}
 	}
+	if model, ok := s.(modelI); ok {
+		return model.getModel().allSelectedFields()
+	}
+	return make([]fieldI, len(s.getSelectors()))
 }
 
+// Delete deletes the rows selected by Where. The delete hooks are called on a new struct of the model.
 func (m *Model) Delete() *DeleteDataset {
-	dataset := goqu.Delete(m.tableName)
-	return &DeleteDataset{
+	dataset := dialect.Delete(m.tableName).Prepared(m.db.prepared())
+	d := &DeleteDataset{
 		model:   m,
 		dataset: dataset,
 		tx:      nil,
+		soft:    m.deletedAt != nil,
+	}
+	if m.hooked(deleteEvent) {
+		d.hooks = []interface{}{reflect.New(m.structType).Interface()}
 	}
+	return d
 }
 
+// Update sets the record. The update hooks are called on a new struct of the model
+// holding the record values, changes of them by before hooks are written.
 func (m *Model) Update(record Record) *UpdateDataset {
-	values := record.toMap()
-	dataset := goqu.Update(m.tableName).Set(values)
-	return &UpdateDataset{
+	values, err := record.toMap(m)
+	if err == nil {
+		m.setTimestamps(values, m.updatedAt)
+	}
+	dataset := dialect.Update(m.tableName).Set(values).Prepared(m.db.prepared())
+	d := &UpdateDataset{
 		model:   m,
 		dataset: dataset,
+		err:     err,
 		tx:      nil,
 	}
+	if err == nil && m.hooked(updateEvent) {
+		receiver := m.newRecordReceiver(record)
+		d.hooks = []interface{}{receiver.model()}
+		d.refresh = func() error {
+			receiver.write(m, values)
+			d.dataset = d.dataset.Set(values)
+			return nil
+		}
+	}
+	return d
 }
 
+// Insert inserts the records. The insert hooks are called on a new struct of the model per record
+// holding the record values, changes of them by before hooks are written.
 func (m *Model) Insert(records ...Record) *InsertDataset {
 	var rows []map[string]interface{}
+	var receivers []*recordReceiver
+	var err error
+	hooked := m.hooked(insertEvent)
 	for _, record := range records {
-		rows = append(rows, record.toMap())
+		row, rowErr := record.toMap(m)
+		if rowErr != nil {
+			if err == nil {
+				err = rowErr
+			}
+			continue
+		}
+		m.setTimestamps(row, m.createdAt, m.updatedAt)
+		rows = append(rows, row)
+		if hooked {
+			receivers = append(receivers, m.newRecordReceiver(record))
+		}
 	}
-	dataset := goqu.Insert(m.tableName).Rows(rows)
-	return &InsertDataset{
+	dataset := dialect.Insert(m.tableName).Rows(rows).Prepared(m.db.prepared())
+	d := &InsertDataset{
 		model:   m,
 		dataset: dataset,
+		err:     err,
 		tx:      nil,
+		rows:    rows,
+	}
+	if hooked {
+		for _, receiver := range receivers {
+			d.hooks = append(d.hooks, receiver.model())
+		}
+		d.refresh = func() error {
+			for i, row := range rows {
+				receivers[i].write(m, row)
+			}
+			d.dataset = d.dataset.ClearRows().Rows(rows)
+			return nil
+		}
+	}
+	return d
+}
+
+// setTimestamps writes the current time into the columns of fields missing in row.
+func (m *Model) setTimestamps(row map[string]interface{}, fields ...fieldI) {
+	for _, field := range fields {
+		if field == nil {
+			continue
+		}
+		if _, ok := row[field.getField()]; !ok {
+			row[field.getField()] = m.db.now()
+		}
+	}
+}
+
+// column returns the column of m the field is written to:
+// its own column or the fk column for fields of nested models.
+func (m *Model) column(field fieldI) (string, error) {
+	model := field.getModel()
+	switch {
+	case model == nil:
+		return "", fmt.Errorf("field is not initialized, call Init on its model")
+	case model == m:
+		return field.getField(), nil
+	case model.parent == m && model.joiner != nil:
+		return model.joiner.From, nil
+	default:
+		return "", fmt.Errorf("field %s does not belong to table %s", field.getField(), m.tableName)
+	}
+}
+
+func (m *Model) getModel() *Model {
+	return m
+}
+
+// InsertModel inserts the values of model structs of this table. Fields with the readonly
+// option are skipped, fields with the default option are written as DEFAULT when NULL
+// and a NULL version field as 1. They are read back into the structs with RETURNING on Exec.
+func (m *Model) InsertModel(models ...modelI) *InsertDataset {
+	d := &InsertDataset{
+		model: m,
+		tx:    nil,
+	}
+	for _, model := range models {
+		d.hooks = append(d.hooks, model)
+	}
+	fields := m.writableFields()
+	var targets []reflect.Value
+	for _, model := range models {
+		target, err := m.structValue(model)
+		if err != nil {
+			d.setError(err)
+			continue
+		}
+		row, err := m.modelRow(target, fields)
+		if err != nil {
+			d.setError(err)
+		}
+		d.rows = append(d.rows, row)
+		targets = append(targets, target)
+	}
+	d.dataset = dialect.Insert(m.tableName).Rows(d.rows).Prepared(m.db.prepared())
+	if generated := m.generatedFields(); len(generated) > 0 {
+		d.Returning(generated...)
+		d.writeBack = &writeBack{model: m, targets: targets, fields: generated}
+	}
+	d.refresh = func() error {
+		var rows []map[string]interface{}
+		for _, target := range targets {
+			row, err := m.modelRow(target, fields)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+		d.rows = rows
+		d.dataset = d.dataset.ClearRows().Rows(rows)
+		return nil
+	}
+	return d
+}
+
+// modelRow returns the values of fields in the model struct target for an insert.
+func (m *Model) modelRow(target reflect.Value, fields []fieldI) (map[string]interface{}, error) {
+	row := make(map[string]interface{})
+	for _, field := range fields {
+		column, _ := m.column(field)
+		targetField, err := m.structField(target, field)
+		if err != nil {
+			return nil, err
+		}
+		value := targetField.getValue()
+		if field.getModel() == m && field.getOptions().hasDefault && isNull(value) {
+			value = goqu.Default()
+		}
+		if (field == m.createdAt || field == m.updatedAt) && isNull(value) {
+			value = m.db.now()
+		}
+		if field == m.version && isNull(value) {
+			value = 1
+		}
+		row[column] = bind(value)
+	}
+	return row, nil
+}
+
+// UpdateModel updates the fields with the values of the model struct. If no fields are passed,
+// all writable fields are updated except the primary key, the created_at field, NULL fields with the default option
+// and fk columns: the fields of fk models are read from the joined rows, which are NULL for filtered out rows.
+// Fields with the readonly or default option are read back on Exec.
+// The row is selected by the primary key, models without one must select rows with Where.
+func (m *Model) UpdateModel(model modelI, fields ...fieldI) *UpdateDataset {
+	target, err := m.structValue(model)
+	d := m.updateModel(target, err, fields, len(fields) == 0)
+	d.hooks = []interface{}{model}
+	if err == nil && len(m.primaryKey) > 0 {
+		d.wherePK(target)
+	}
+	return d
+}
+
+// Save updates only the dirty fields of the model struct, the row is selected by the values
+// of the keys fields (the primary key if none are passed). Exec resets the saved fields
+// and does nothing if no field is dirty.
+func (m *Model) Save(model modelI, keys ...fieldI) *UpdateDataset {
+	if len(keys) == 0 {
+		keys = m.primaryKey
+	}
+	target, err := m.structValue(model)
+	if err == nil && len(keys) == 0 {
+		err = fmt.Errorf("save on table %s requires key fields or a primary key", m.tableName)
+	}
+	var fields, dirty []fieldI
+	if err == nil {
+		for _, field := range m.writableFields() {
+			if containsField(keys, field) {
+				continue
+			}
+			targetField, fieldErr := m.structField(target, field)
+			if fieldErr != nil {
+				err = fieldErr
+				break
+			}
+			if targetField.IsDirty() {
+				fields = append(fields, field)
+				dirty = append(dirty, targetField)
+			}
+		}
+	}
+	d := m.updateModel(target, err, fields, false)
+	d.hooks = []interface{}{model}
+	if err != nil {
+		return d
+	}
+	d.dirty = dirty
+	d.noop = len(fields) == 0
+	for _, key := range keys {
+		if key.getModel() != m {
+			d.setError(fmt.Errorf("key field %s is not a field of table %s", key.getField(), m.tableName))
+			return d
+		}
+	}
+	values, err := m.structValues(target, keys)
+	if err != nil {
+		d.setError(err)
+		return d
+	}
+	return d.Where(keyConditions(keys, values)...)
+}
+
+// updateModel returns the update of fields with the values of target, err is the error of
+// getting target. With allFields the fields UpdateModel skips without a field list are skipped.
+func (m *Model) updateModel(target reflect.Value, err error, fields []fieldI, allFields bool) *UpdateDataset {
+	d := &UpdateDataset{
+		model:        m,
+		tx:           nil,
+		requireWhere: true,
+	}
+	if allFields {
+		fields = m.writableFields()
+	}
+	if err != nil {
+		d.setError(err)
+		d.dataset = dialect.Update(m.tableName)
+		return d
+	}
+	values, err := m.modelValues(target, fields, allFields)
+	if err != nil {
+		d.setError(err)
+	}
+	d.dataset = dialect.Update(m.tableName).Set(values).Prepared(m.db.prepared())
+	generated := m.generatedFields()
+	if m.version != nil {
+		d.whereVersion(target)
+	}
+	if len(generated) > 0 {
+		d.Returning(generated...)
+		d.writeBack = &writeBack{model: m, targets: []reflect.Value{target}, fields: generated}
+	}
+	d.refresh = func() error {
+		values, err := m.modelValues(target, fields, allFields)
+		if err != nil {
+			return err
+		}
+		d.dataset = d.dataset.Set(values)
+		return nil
+	}
+	return d
+}
+
+// modelValues returns the values of fields in the model struct target for an update, see updateModel.
+func (m *Model) modelValues(target reflect.Value, fields []fieldI, allFields bool) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, field := range fields {
+		if field == m.version || field == m.updatedAt {
+			continue
+		}
+		if field.getModel() == m && field.getOptions().readonly {
+			return nil, fmt.Errorf("field %s is readonly", field.getField())
+		}
+		column, err := m.column(field)
+		if err != nil {
+			return nil, err
+		}
+		targetField, err := m.structField(target, field)
+		if err != nil {
+			return nil, err
+		}
+		value := targetField.getValue()
+		if allFields && (field.getModel() != m || m.isPK(field) || field == m.createdAt || field.getOptions().hasDefault && isNull(value)) {
+			continue
+		}
+		values[column] = bind(value)
+	}
+	if m.updatedAt != nil {
+		values[m.updatedAt.getField()] = m.db.now()
+	}
+	if m.version != nil {
+		values[m.version.getField()] = goqu.L("? + 1", m.version.getIdent())
+	}
+	return values, nil
+}
+
+// DeleteModel deletes the row of the model struct selected by the primary key,
+// models without one must select the row with Where.
+func (m *Model) DeleteModel(model modelI) *DeleteDataset {
+	d := &DeleteDataset{
+		model:        m,
+		dataset:      dialect.Delete(m.tableName).Prepared(m.db.prepared()),
+		tx:           nil,
+		requireWhere: true,
+		soft:         m.deletedAt != nil,
+		hooks:        []interface{}{model},
+	}
+	target, err := m.structValue(model)
+	if err != nil {
+		d.setError(err)
+		return d
+	}
+	if len(m.primaryKey) > 0 {
+		d.wherePK(target)
+	}
+	return d
+}
+
+// Columns lists the columns of InsertFrom.
+func Columns(fields ...fieldI) []fieldI {
+	return fields
+}
+
+// InsertFrom inserts the rows selected by ds into columns (INSERT INTO ... SELECT).
+// The number of columns must match the select list, and a selected field can not
+// be inserted into its own column or a column named like it at another position.
+func (m *Model) InsertFrom(columns []fieldI, ds *SelectDataset) *InsertDataset {
+	d := &InsertDataset{
+		model: m,
+		tx:    nil,
+	}
+	var cols []interface{}
+	var names []string
+	for _, field := range columns {
+		column, err := m.column(field)
+		if err != nil {
+			d.setError(err)
+			continue
+		}
+		cols = append(cols, column)
+		names = append(names, column)
+	}
+	if len(columns) != len(ds.selected) {
+		d.setError(fmt.Errorf("insert from select: %d columns, %d selected", len(columns), len(ds.selected)))
 	}
+	aliases := ds.selectedNames()
+	for i, field := range ds.selected {
+		var selected []string
+		if i < len(aliases) && aliases[i] != "" {
+			selected = append(selected, aliases[i])
+		}
+		if field != nil {
+			column, err := m.column(field)
+			if err != nil {
+				column = field.getField()
+			}
+			selected = append(selected, column)
+		}
+		for j, name := range names {
+			for _, s := range selected {
+				if s == name && i != j {
+					d.setError(fmt.Errorf("insert from select: %s is selected at position %d but inserted at %d", name, i+1, j+1))
+				}
+			}
+		}
+	}
+	d.setError(ds.err)
+	d.dataset = dialect.Insert(m.tableName).Cols(cols...).FromQuery(ds.build()).Prepared(m.db.prepared())
+	return d
 }
 
 func (m *Model) getSelectors() []interface{} {
@@ -233,6 +702,15 @@ func (m *Model) allSelectors() []interface{} {
 	return selectors
 }
 
+// allSelectedFields returns the fields in the order of allSelectors.
+func (m *Model) allSelectedFields() []fieldI {
+	fields := append([]fieldI{}, m.fields...)
+	for _, fk := range m.fkModels {
+		fields = append(fields, fk.allSelectedFields()...)
+	}
+	return fields
+}
+
 func (m *Model) allJoiners() []*joiner {
 	var joiners []*joiner
 	joiners = append(joiners, m.joiner)
@@ -0,0 +1,112 @@
+package pgs
+
+import (
+	"context"
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"reflect"
+)
+
+// PrimaryKey returns the fields tagged with pk in declaration order.
+func (m *Model) PrimaryKey() []fieldI {
+	return m.primaryKey
+}
+
+// Get scans the row with the primary key keys into dst.
+func (m *Model) Get(ctx context.Context, dst interface{}, keys ...interface{}) error {
+	conditions, err := m.pkConditions(keys)
+	if err != nil {
+		return err
+	}
+	return m.Select().Where(conditions...).ScanOneContext(ctx, dst)
+}
+
+// Exists reports whether the row with the primary key keys exists.
+func (m *Model) Exists(ctx context.Context, keys ...interface{}) (bool, error) {
+	conditions, err := m.pkConditions(keys)
+	if err != nil {
+		return false, err
+	}
+	sd := m.Select(L("1")).Where(conditions...)
+	query, args, err := bindSQL(dialect.Select(goqu.L("EXISTS ?", sd.build())).Prepared(m.db.prepared()))
+	if err != nil {
+		return false, err
+	}
+	var exists bool
+	q, err := sd.querier(ctx)
+	if err != nil {
+		return false, err
+	}
+	err = pgxscan.Get(ctx, q, &exists, query, args...)
+	return exists, err
+}
+
+// DeleteByPK deletes the row with the primary key keys,
+// the delete hooks are called on a new struct of the model holding the keys.
+func (m *Model) DeleteByPK(keys ...interface{}) *DeleteDataset {
+	d := m.Delete()
+	conditions, err := m.pkConditions(keys)
+	if err != nil {
+		d.setError(err)
+		return d
+	}
+	if d.hooks != nil {
+		record := Record{}
+		for i, field := range m.primaryKey {
+			record[field] = keys[i]
+		}
+		d.hooks = []interface{}{m.newRecordReceiver(record).model()}
+	}
+	return d.Where(conditions...)
+}
+
+func (m *Model) pkConditions(keys []interface{}) ([]Conditional, error) {
+	if len(m.primaryKey) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key, tag fields with pk", m.tableName)
+	}
+	if len(keys) != len(m.primaryKey) {
+		return nil, fmt.Errorf("table %s has %d primary key fields, got %d values", m.tableName, len(m.primaryKey), len(keys))
+	}
+	return keyConditions(m.primaryKey, keys), nil
+}
+
+// keyConditions returns the conditions fields[i] = values[i].
+func keyConditions(fields []fieldI, values []interface{}) []Conditional {
+	var conditions []Conditional
+	for i, field := range fields {
+		conditions = append(conditions, Condition{
+			Field:   field,
+			Op:      opEq,
+			Value:   values[i],
+			joiners: field.getJoiners(),
+		})
+	}
+	return conditions
+}
+
+// structPK returns the primary key values of the model struct.
+func (m *Model) structPK(target reflect.Value) ([]interface{}, error) {
+	return m.structValues(target, m.primaryKey)
+}
+
+// structValues returns the values of the key fields in the model struct, NULL keys are an error.
+func (m *Model) structValues(target reflect.Value, fields []fieldI) ([]interface{}, error) {
+	var values []interface{}
+	for _, field := range fields {
+		targetField, err := m.structField(target, field)
+		if err != nil {
+			return nil, err
+		}
+		value := targetField.getValue()
+		if isNull(value) {
+			return nil, fmt.Errorf("key field %s of table %s is NULL", field.getField(), m.tableName)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func (m *Model) isPK(field fieldI) bool {
+	return containsField(m.primaryKey, field)
+}
@@ -0,0 +1,258 @@
+package pgs
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// relation is a has-many relation declared with a slice of models tagged with rel:"column[,key]".
+// column is the column of the child table referring to the key field of the parent,
+// the primary key by default.
+type relation struct {
+	name     string
+	index    int
+	elemType reflect.Type
+	column   string
+	// key is the parent field referred to by column, keyColumn its column if set in the tag
+	key       fieldI
+	keyColumn string
+
+	// child is the model of the children, initialized on first use
+	// because the child may refer back to the parent
+	once     sync.Once
+	child    *Model
+	childFk  fieldI
+	childErr error
+}
+
+type preload struct {
+	relation   *relation
+	conditions []Conditional
+}
+
+// newRelation returns the relation of the struct field, the key is resolved by resolveRelations.
+func newRelation(field reflect.StructField, index int, tag string) (*relation, error) {
+	if field.Type.Kind() != reflect.Slice || field.Type.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("rel field %s must be a slice of models", field.Name)
+	}
+	if _, ok := reflect.New(field.Type.Elem()).Interface().(modelI); !ok {
+		return nil, fmt.Errorf("rel field %s must be a slice of models", field.Name)
+	}
+	values := strings.Split(tag, ",")
+	if len(values) > 2 || values[0] == "" {
+		return nil, fmt.Errorf("uncorrect value in rel tag. Expected column[,key]. Goted: %s", tag)
+	}
+	r := &relation{
+		name:     field.Name,
+		index:    index,
+		elemType: field.Type.Elem(),
+		column:   values[0],
+	}
+	if len(values) == 2 {
+		r.keyColumn = values[1]
+	}
+	return r, nil
+}
+
+// resolveRelations sets the parent key fields of the relations of m.
+func (m *Model) resolveRelations() error {
+	for _, r := range m.relations {
+		if r.keyColumn == "" {
+			if len(m.primaryKey) != 1 {
+				return fmt.Errorf("rel field %s requires a single primary key field or a key in the rel tag", r.name)
+			}
+			r.key = m.primaryKey[0]
+			continue
+		}
+		for _, field := range m.fields {
+			if field.getField() == r.keyColumn {
+				r.key = field
+			}
+		}
+		if r.key == nil {
+			return fmt.Errorf("rel field %s: table %s has no field %s", r.name, m.tableName, r.keyColumn)
+		}
+	}
+	return nil
+}
+
+// init initializes the model of the children and finds the field referring to the parent.
+func (r *relation) init(db *DbClient) error {
+	r.once.Do(func() {
+		child := reflect.New(r.elemType).Interface().(modelI)
+		if err := child.Init(db, child); err != nil {
+			r.childErr = err
+			return
+		}
+		r.child = child.getModel()
+		for _, field := range r.child.fields {
+			if field.getField() == r.column {
+				r.childFk = field
+			}
+		}
+		if r.childFk == nil {
+			r.childErr = fmt.Errorf("rel field %s: table %s has no field %s", r.name, r.child.tableName, r.column)
+		}
+	})
+	return r.childErr
+}
+
+// Preload loads the children of a has-many relation of the model into the scanned structs.
+// relation is the rel field of the struct the model was initialized with (e.g. &user.Posts),
+// the children are selected for all scanned rows with one query filtered by conditions.
+func (sd *SelectDataset) Preload(relation interface{}, conditions ...Conditional) *SelectDataset {
+	r, err := sd.model.relation(relation)
+	if err != nil {
+		sd.setError(err)
+		return sd
+	}
+	sd.preloads = append(sd.preloads, preload{relation: r, conditions: conditions})
+	return sd
+}
+
+// relation returns the relation whose field of the struct the model was initialized with is ptr.
+func (m *Model) relation(ptr interface{}) (*relation, error) {
+	value := reflect.ValueOf(ptr)
+	self := reflect.ValueOf(m.self)
+	if value.Kind() == reflect.Pointer && self.Kind() == reflect.Pointer {
+		for _, r := range m.relations {
+			field := self.Elem().Field(r.index)
+			if field.Addr().Pointer() == value.Pointer() && field.Addr().Type() == value.Type() {
+				return r, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%T is not a rel field of table %s", ptr, m.tableName)
+}
+
+// preload runs the preloads for the structs or the slice of structs dst points to.
+func (sd *SelectDataset) preload(ctx context.Context, dst interface{}) error {
+	if len(sd.preloads) == 0 {
+		return nil
+	}
+	parents, err := sd.model.preloadTargets(dst)
+	if err != nil {
+		return err
+	}
+	for _, p := range sd.preloads {
+		if err := sd.preloadRelation(ctx, p, parents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// preloadTargets returns the model structs in dst.
+func (m *Model) preloadTargets(dst interface{}) ([]reflect.Value, error) {
+	value := reflect.ValueOf(dst)
+	if value.Kind() != reflect.Pointer || value.IsNil() {
+		return nil, fmt.Errorf("preload: expected pointer, got %T", dst)
+	}
+	value = value.Elem()
+	if value.Type() == m.structType {
+		return []reflect.Value{value}, nil
+	}
+	if value.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("preload: expected models of table %s, got %T", m.tableName, dst)
+	}
+	var targets []reflect.Value
+	for i := 0; i < value.Len(); i++ {
+		elem := value.Index(i)
+		if elem.Kind() == reflect.Pointer {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+		if elem.Type() != m.structType {
+			return nil, fmt.Errorf("preload: expected models of table %s, got %T", m.tableName, dst)
+		}
+		targets = append(targets, elem)
+	}
+	return targets, nil
+}
+
+func (sd *SelectDataset) preloadRelation(ctx context.Context, p preload, parents []reflect.Value) error {
+	r := p.relation
+	if err := r.init(sd.model.db); err != nil {
+		return err
+	}
+	var keys []interface{}
+	seen := make(map[interface{}]bool)
+	for _, parent := range parents {
+		key, err := sd.model.structField(parent, r.key)
+		if err != nil {
+			return err
+		}
+		value, err := keyValue(key.getValue())
+		if err != nil {
+			return err
+		}
+		if value != nil && !seen[value] {
+			seen[value] = true
+			keys = append(keys, value)
+		}
+	}
+
+	children := reflect.New(reflect.SliceOf(r.elemType))
+	if len(keys) > 0 {
+		in := Condition{
+			Field:   r.childFk,
+			Op:      opIn,
+			Value:   keys,
+			joiners: r.childFk.getJoiners(),
+		}
+		conditions := append([]Conditional{in}, p.conditions...)
+		err := r.child.Select().Where(conditions...).WithTx(sd.tx).ScanContext(ctx, children.Interface())
+		if err != nil {
+			return err
+		}
+	}
+
+	groups := make(map[interface{}]reflect.Value)
+	for i := 0; i < children.Elem().Len(); i++ {
+		child := children.Elem().Index(i)
+		fk, err := r.child.structField(child, r.childFk)
+		if err != nil {
+			return err
+		}
+		value, err := keyValue(fk.getValue())
+		if err != nil {
+			return err
+		}
+		group, ok := groups[value]
+		if !ok {
+			group = reflect.MakeSlice(reflect.SliceOf(r.elemType), 0, 1)
+		}
+		groups[value] = reflect.Append(group, child)
+	}
+	for _, parent := range parents {
+		key, _ := sd.model.structField(parent, r.key)
+		value, _ := keyValue(key.getValue())
+		group, ok := groups[value]
+		if !ok || value == nil {
+			group = reflect.MakeSlice(reflect.SliceOf(r.elemType), 0, 0)
+		}
+		parent.Field(r.index).Set(group)
+	}
+	return nil
+}
+
+// keyValue returns the driver value of a key to compare keys of parents and children, nil for NULL.
+func keyValue(value interface{}) (interface{}, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		var err error
+		value, err = valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if value != nil && !reflect.TypeOf(value).Comparable() {
+		return nil, fmt.Errorf("rel key of type %T is not comparable", value)
+	}
+	return value, nil
+}
@@ -0,0 +1,83 @@
+package pgs
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"time"
+)
+
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+
+	defaultMaxAttempts = 3
+)
+
+type RetryOptions struct {
+	// MaxAttempts is the total number of runs, 3 if not set.
+	MaxAttempts int
+	// Backoff returns the delay before the given retry (starting from 1).
+	// No delay if not set.
+	Backoff func(attempt int) time.Duration
+	// OnRetry is called before each retry with the error that caused it.
+	OnRetry func(attempt int, err error)
+}
+
+// ExponentialBackoff returns a Backoff doubling base on every retry up to max.
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			return max
+		}
+		return delay
+	}
+}
+
+// IsRetryable reports whether err is a serialization failure (40001)
+// or a deadlock (40P01), after which the transaction can be re-run.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == sqlStateSerializationFailure || pgErr.Code == sqlStateDeadlockDetected
+}
+
+// InTxRetry runs fn with InTx and re-runs it while it fails with a retryable error.
+// fn must be safe to run several times. If ctx already carries a transaction,
+// fn runs once in a nested transaction: the error aborts the outer transaction
+// and the retry belongs to whoever started it.
+func (cli *DbClient) InTxRetry(ctx context.Context, opts TxOptions, retry RetryOptions, fn func(tx *Tx) error) error {
+	if _, ok := TxFromContext(ctx); ok {
+		return cli.InTx(ctx, opts, fn)
+	}
+	maxAttempts := retry.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = cli.InTx(ctx, opts, fn)
+		if err == nil || !IsRetryable(err) || attempt >= maxAttempts {
+			return err
+		}
+		if retry.OnRetry != nil {
+			retry.OnRetry(attempt, err)
+		}
+		if retry.Backoff != nil {
+			timer := time.NewTimer(retry.Backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+	}
+}
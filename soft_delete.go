@@ -0,0 +1,67 @@
+package pgs
+
+import (
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+)
+
+type deletedScope int
+
+const (
+	// excludeDeleted filters out soft deleted rows of the model and of its fk models
+	excludeDeleted deletedScope = iota
+	withDeleted
+	onlyDeleted
+)
+
+// WithDeleted selects soft deleted rows too.
+func (sd *SelectDataset) WithDeleted() *SelectDataset {
+	sd.deleted = withDeleted
+	return sd
+}
+
+// OnlyDeleted selects only soft deleted rows of the model, fk models are joined with their deleted rows.
+func (sd *SelectDataset) OnlyDeleted() *SelectDataset {
+	sd.deleted = onlyDeleted
+	return sd
+}
+
+// HardDelete deletes the rows of a soft delete model with DELETE.
+func (d *DeleteDataset) HardDelete() *DeleteDataset {
+	d.soft = false
+	return d
+}
+
+// deletedCondition returns the condition on the soft delete field of the model, nil if there is none.
+func (sd *SelectDataset) deletedCondition() exp.Expression {
+	deletedAt := sd.model.deletedAt
+	if deletedAt == nil {
+		return nil
+	}
+	switch sd.deleted {
+	case excludeDeleted:
+		return deletedAt.getIdent().IsNull()
+	case onlyDeleted:
+		return deletedAt.getIdent().IsNotNull()
+	}
+	return nil
+}
+
+// fkJoinOn returns the join condition of the fk, filtering out soft deleted rows
+// of the joined model by default.
+func (sd *SelectDataset) fkJoinOn(joiner *joiner) exp.JoinCondition {
+	if !sd.filtersDeleted(joiner.model) {
+		return joiner.On
+	}
+	on, ok := joiner.On.(exp.JoinOnCondition)
+	if !ok {
+		return joiner.On
+	}
+	return goqu.On(on.On(), joiner.model.deletedAt.getIdent().IsNull())
+}
+
+// filtersDeleted reports whether the fk join of the model filters out soft deleted rows.
+// Such joins are left joins unless set by InnerJoin, as a deleted fk row must not drop the root row.
+func (sd *SelectDataset) filtersDeleted(m *Model) bool {
+	return m.deletedAt != nil && sd.deleted == excludeDeleted
+}
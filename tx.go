@@ -0,0 +1,110 @@
+package pgs
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+type TxOptions struct {
+	IsoLevel   pgx.TxIsoLevel
+	ReadOnly   bool
+	Deferrable bool
+}
+
+func (o TxOptions) pgxOptions() pgx.TxOptions {
+	opts := pgx.TxOptions{IsoLevel: o.IsoLevel}
+	if o.ReadOnly {
+		opts.AccessMode = pgx.ReadOnly
+	}
+	if o.Deferrable {
+		opts.DeferrableMode = pgx.Deferrable
+	}
+	return opts
+}
+
+// Tx is a transaction started by DbClient.InTx. It can be passed to WithTx
+// as a regular pgx.Tx, or its Context can be passed to WithContext so that
+// datasets pick it up automatically.
+type Tx struct {
+	pgx.Tx
+	ctx context.Context
+}
+
+type txKey struct{}
+
+// Context returns a context carrying the transaction.
+func (tx *Tx) Context() context.Context {
+	return tx.ctx
+}
+
+// TxFromContext returns the transaction stored in ctx by DbClient.InTx.
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	return tx, ok
+}
+
+// wrapTx returns tx as a Tx carrying it in a context derived from ctx.
+func wrapTx(ctx context.Context, tx pgx.Tx) *Tx {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	wrapped := &Tx{Tx: tx}
+	wrapped.ctx = context.WithValue(ctx, txKey{}, wrapped)
+	return wrapped
+}
+
+// InTx runs fn in a transaction. The transaction is committed if fn returns nil
+// and rolled back if fn returns an error or panics.
+// If ctx already carries a transaction, fn runs in a nested transaction
+// (see Nested) and opts are ignored.
+func (cli *DbClient) InTx(ctx context.Context, opts TxOptions, fn func(tx *Tx) error) error {
+	if parent, ok := TxFromContext(ctx); ok {
+		return Nested(ctx, parent, fn)
+	}
+	pgxTx, err := cli.Pool.BeginTx(ctx, opts.pgxOptions())
+	if err != nil {
+		return err
+	}
+	return runTx(ctx, pgxTx, fn)
+}
+
+// InTx runs fn in a transaction nested in tx.
+func (tx *Tx) InTx(fn func(tx *Tx) error) error {
+	return Nested(tx.ctx, tx, fn)
+}
+
+// Nested runs fn in a nested transaction on top of tx. It creates a SAVEPOINT,
+// releases it if fn returns nil and rolls back to it if fn returns an error
+// or panics, leaving the outer transaction usable.
+func Nested(ctx context.Context, tx pgx.Tx, fn func(tx *Tx) error) error {
+	pgxTx, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	return runTx(ctx, pgxTx, fn)
+}
+
+func runTx(ctx context.Context, pgxTx pgx.Tx, fn func(tx *Tx) error) (err error) {
+	tx := &Tx{Tx: pgxTx}
+	tx.ctx = context.WithValue(ctx, txKey{}, tx)
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = pgxTx.Rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		if rbErr := pgxTx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rbErr)
+		}
+		return err
+	}
+	return pgxTx.Commit(ctx)
+}
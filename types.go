@@ -2,15 +2,19 @@ package pgs
 
 type Record map[fieldI]interface{}
 
-func (r Record) toMap() map[string]interface{} {
+func (r Record) toMap(m *Model) (map[string]interface{}, error) {
 	insertMap := make(map[string]interface{})
 	for field, value := range r {
-		model := field.getModel()
-		if model.joiner != nil {
-			insertMap[model.joiner.From] = value
+		column, err := m.column(field)
+		if err != nil {
+			return nil, err
+		}
+		if valueField, ok := value.(fieldI); ok {
+			value = valueField.getIdent()
 		} else {
-			insertMap[field.getField()] = value
+			value = bind(value)
 		}
+		insertMap[column] = value
 	}
-	return insertMap
+	return insertMap, nil
 }
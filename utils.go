@@ -1,6 +1,8 @@
 package pgs
 
 import (
+	"database/sql/driver"
+	"github.com/jackc/pgx/v5/pgtype"
 	"strings"
 	"unicode"
 )
@@ -34,3 +36,40 @@ func toSnakeCase(str string) string {
 
 	return result
 }
+
+func placeholders(count int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", count), ", ")
+}
+
+func isNull(value interface{}) bool {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		return err == nil && v == nil
+	}
+	return value == nil
+}
+
+func containsField(fields []fieldI, field fieldI) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func isInteger(value interface{}) bool {
+	switch value.(type) {
+	case pgtype.Int2, pgtype.Int4, pgtype.Int8:
+		return true
+	}
+	return false
+}
+
+func isTimestamp(value interface{}) bool {
+	switch value.(type) {
+	case pgtype.Timestamptz, pgtype.Timestamp:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,293 @@
+package pgs
+
+import (
+	"fmt"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"strings"
+)
+
+type FrameBound string
+
+const (
+	UnboundedPreceding FrameBound = "UNBOUNDED PRECEDING"
+	CurrentRow         FrameBound = "CURRENT ROW"
+	UnboundedFollowing FrameBound = "UNBOUNDED FOLLOWING"
+)
+
+func Preceding(offset uint) FrameBound {
+	return FrameBound(fmt.Sprintf("%d PRECEDING", offset))
+}
+
+func Following(offset uint) FrameBound {
+	return FrameBound(fmt.Sprintf("%d FOLLOWING", offset))
+}
+
+// WindowSpec describes a window: PARTITION BY, ORDER BY and frame.
+type WindowSpec struct {
+	name      string
+	parent    string
+	partition []interface{}
+	order     []exp.OrderedExpression
+	frame     string
+	joiners   []*joiner
+}
+
+// Window creates an inline window specification for Over.
+func Window() WindowSpec {
+	return WindowSpec{}
+}
+
+// NamedWindow creates a window specification for SelectDataset.Window,
+// which can then be referenced with OverName or Inherit.
+func NamedWindow(name string) WindowSpec {
+	return WindowSpec{name: name}
+}
+
+// Inherit builds the window on top of the named window.
+func (w WindowSpec) Inherit(name string) WindowSpec {
+	w.parent = name
+	return w
+}
+
+func (w WindowSpec) PartitionBy(fields ...Ordered) WindowSpec {
+	w.partition = w.partition[:len(w.partition):len(w.partition)]
+	w.joiners = w.joiners[:len(w.joiners):len(w.joiners)]
+	for _, field := range fields {
+		w.joiners = append(w.joiners, field.getJoiners()...)
+		w.partition = append(w.partition, field.getIdent())
+	}
+	return w
+}
+
+func (w WindowSpec) OrderAsc(fields ...Ordered) WindowSpec {
+	return w.orderAppend(fields, func(ident exp.IdentifierExpression) exp.OrderedExpression {
+		return ident.Asc()
+	})
+}
+
+func (w WindowSpec) OrderDesc(fields ...Ordered) WindowSpec {
+	return w.orderAppend(fields, func(ident exp.IdentifierExpression) exp.OrderedExpression {
+		return ident.Desc()
+	})
+}
+
+func (w WindowSpec) orderAppend(fields []Ordered, order func(exp.IdentifierExpression) exp.OrderedExpression) WindowSpec {
+	w.order = w.order[:len(w.order):len(w.order)]
+	w.joiners = w.joiners[:len(w.joiners):len(w.joiners)]
+	for _, field := range fields {
+		w.joiners = append(w.joiners, field.getJoiners()...)
+		w.order = append(w.order, order(field.getIdent()))
+	}
+	return w
+}
+
+// Rows sets the frame ROWS BETWEEN start AND end.
+func (w WindowSpec) Rows(start, end FrameBound) WindowSpec {
+	w.frame = fmt.Sprintf("ROWS BETWEEN %s AND %s", start, end)
+	return w
+}
+
+// Range sets the frame RANGE BETWEEN start AND end.
+func (w WindowSpec) Range(start, end FrameBound) WindowSpec {
+	w.frame = fmt.Sprintf("RANGE BETWEEN %s AND %s", start, end)
+	return w
+}
+
+func (w WindowSpec) expression() exp.LiteralExpression {
+	var parts []string
+	var args []interface{}
+	if w.parent != "" {
+		parts = append(parts, "?")
+		args = append(args, goqu.I(w.parent))
+	}
+	if len(w.partition) > 0 {
+		parts = append(parts, "PARTITION BY "+placeholders(len(w.partition)))
+		args = append(args, w.partition...)
+	}
+	if len(w.order) > 0 {
+		parts = append(parts, "ORDER BY "+placeholders(len(w.order)))
+		for _, order := range w.order {
+			args = append(args, order)
+		}
+	}
+	if w.frame != "" {
+		parts = append(parts, w.frame)
+	}
+	return goqu.L("("+strings.Join(parts, " ")+")", args...)
+}
+
+func (w WindowSpec) definition() (exp.WindowExpression, error) {
+	if w.name == "" {
+		return nil, fmt.Errorf("window definition requires a name, use NamedWindow")
+	}
+	var window exp.WindowExpression
+	if w.parent != "" {
+		window = goqu.W(w.name, w.parent)
+	} else {
+		window = goqu.W(w.name)
+	}
+	if len(w.partition) > 0 {
+		window = window.PartitionBy(w.partition...)
+	}
+	if len(w.order) > 0 {
+		var order []interface{}
+		for _, o := range w.order {
+			order = append(order, o)
+		}
+		window = window.OrderBy(order...)
+	}
+	if w.frame != "" {
+		return framedWindow{
+			WindowExpression:  window,
+			LiteralExpression: goqu.L("? AS ?", goqu.I(w.name), w.expression()),
+		}, nil
+	}
+	return window, nil
+}
+
+// framedWindow is a named window rendered from its literal, goqu renders
+// window definitions without frames.
+type framedWindow struct {
+	exp.WindowExpression
+	exp.LiteralExpression
+}
+
+func (w framedWindow) Clone() exp.Expression {
+	return w
+}
+
+func (w framedWindow) Expression() exp.Expression {
+	return w
+}
+
+type WindowExpression struct {
+	function   exp.LiteralExpression
+	columnName string
+	over       exp.LiteralExpression
+	as         string
+	joiners    []*joiner
+	err        error
+}
+
+func newWindowFunction(name string, field fieldI, args ...interface{}) WindowExpression {
+	var joiners []*joiner
+	if field != nil {
+		joiners = field.getJoiners()
+		args = append([]interface{}{field.getIdent()}, args...)
+	}
+	return WindowExpression{
+		function:   goqu.L(name+"("+placeholders(len(args))+")", args...),
+		columnName: strings.ToLower(name),
+		over:       Window().expression(),
+		joiners:    joiners,
+	}
+}
+
+func RowNumber() WindowExpression {
+	return newWindowFunction("ROW_NUMBER", nil)
+}
+
+func Rank() WindowExpression {
+	return newWindowFunction("RANK", nil)
+}
+
+func DenseRank() WindowExpression {
+	return newWindowFunction("DENSE_RANK", nil)
+}
+
+func PercentRank() WindowExpression {
+	return newWindowFunction("PERCENT_RANK", nil)
+}
+
+func CumeDist() WindowExpression {
+	return newWindowFunction("CUME_DIST", nil)
+}
+
+func Ntile(buckets int) WindowExpression {
+	return newWindowFunction("NTILE", nil, buckets)
+}
+
+// Lag accepts optional offset and default value.
+func Lag(field fieldI, args ...interface{}) WindowExpression {
+	return newWindowFunction("LAG", field, args...)
+}
+
+// Lead accepts optional offset and default value.
+func Lead(field fieldI, args ...interface{}) WindowExpression {
+	return newWindowFunction("LEAD", field, args...)
+}
+
+func FirstValue(field fieldI) WindowExpression {
+	return newWindowFunction("FIRST_VALUE", field)
+}
+
+func LastValue(field fieldI) WindowExpression {
+	return newWindowFunction("LAST_VALUE", field)
+}
+
+func NthValue(field fieldI, n int) WindowExpression {
+	return newWindowFunction("NTH_VALUE", field, n)
+}
+
+// Over turns the aggregate into a window function.
+func (a AggregateExpression) Over(window WindowSpec) WindowExpression {
+	w := WindowExpression{
+		function:   a.expression(),
+		columnName: strings.ToLower(a.name),
+		as:         a.as,
+		joiners:    a.joiners,
+		err:        a.err,
+	}
+	return w.Over(window)
+}
+
+// OverName turns the aggregate into a window function over the named window.
+func (a AggregateExpression) OverName(name string) WindowExpression {
+	return a.Over(Window()).OverName(name)
+}
+
+func (w WindowExpression) Over(window WindowSpec) WindowExpression {
+	w.over = window.expression()
+	w.joiners = append(w.joiners[:len(w.joiners):len(w.joiners)], window.joiners...)
+	return w
+}
+
+// OverName uses the window defined by SelectDataset.Window (OVER name, which unlike
+// OVER (name) accepts windows with a frame).
+func (w WindowExpression) OverName(name string) WindowExpression {
+	w.over = goqu.L("?", goqu.I(name))
+	return w
+}
+
+func (w WindowExpression) As(as string) WindowExpression {
+	w.as = as
+	return w
+}
+
+func (w WindowExpression) expression() exp.LiteralExpression {
+	return goqu.L("? OVER ?", w.function, w.over)
+}
+
+func (w WindowExpression) getSelectors() []interface{} {
+	if w.as != "" {
+		return []interface{}{w.expression().As(w.as)}
+	}
+	return []interface{}{w.expression()}
+}
+
+func (w WindowExpression) getJoiners() []*joiner {
+	return w.joiners
+}
+
+// getIdent refers to the output column, so the result can be used in OrderAsc/OrderDesc.
+func (w WindowExpression) getIdent() exp.IdentifierExpression {
+	if w.as != "" {
+		return goqu.I(w.as)
+	}
+	return goqu.I(w.columnName)
+}
+
+func (w WindowExpression) getError() error {
+	return w.err
+}
@@ -0,0 +1,92 @@
+package pgs
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"reflect"
+)
+
+// writeBack scans the RETURNING rows of a model write into the model structs, in order.
+type writeBack struct {
+	model   *Model
+	targets []reflect.Value
+	fields  []fieldI
+}
+
+// query runs the statement and scans its rows into the targets starting from offset.
+// It returns the number of rows read.
+func (w *writeBack) query(ctx context.Context, q querier, offset int, query string, args ...interface{}) (int, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return w.scan(rows, offset)
+}
+
+func (w *writeBack) scan(rows pgx.Rows, offset int) (int, error) {
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		i := offset + n
+		n++
+		if i >= len(w.targets) {
+			continue
+		}
+		dest := make([]interface{}, len(w.fields))
+		for j, field := range w.fields {
+			target, err := w.model.structField(w.targets[i], field)
+			if err != nil {
+				return n, err
+			}
+			dest[j] = target.getValuePtr()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+	}
+	return n, rows.Err()
+}
+
+// structValue returns the struct behind model, which must be of the type m was initialized with.
+func (m *Model) structValue(model modelI) (reflect.Value, error) {
+	value := reflect.ValueOf(model)
+	if value.Kind() != reflect.Pointer || value.IsNil() || value.Elem().Type() != m.structType {
+		return reflect.Value{}, fmt.Errorf("%T is not a model of table %s", model, m.tableName)
+	}
+	return value.Elem(), nil
+}
+
+// structField returns the field of target corresponding to the field of m.
+func (m *Model) structField(target reflect.Value, field fieldI) (fieldI, error) {
+	path, err := m.fieldPath(field)
+	if err != nil {
+		return nil, err
+	}
+	return target.FieldByIndex(path).Addr().Interface().(fieldI), nil
+}
+
+// writableFields returns the fields written by model writes: fields of m without
+// the readonly option and the fk target fields of nested models.
+func (m *Model) writableFields() []fieldI {
+	var fields []fieldI
+	for _, field := range m.copyColumns() {
+		if field.getModel() == m && field.getOptions().readonly {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// generatedFields returns the fields read back after model writes.
+func (m *Model) generatedFields() []fieldI {
+	var fields []fieldI
+	for _, field := range m.fields {
+		options := field.getOptions()
+		if options.readonly || options.hasDefault || field == m.createdAt || field == m.updatedAt || field == m.version {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}